@@ -1,15 +1,100 @@
 package config
 
 import (
+	"fmt"
 	"github.com/gomsr/atom-uid/generator"
+	"github.com/gomsr/atom-uid/utilu"
 	"github.com/gomsr/atom-uid/worker"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	IdAssigner worker.Type    `mapstructure:"id_assigner" json:"id_assigner" yaml:"id_assigner"`
-	Generator  generator.Type `mapstructure:"generator" json:"generator" yaml:"generator"`
+	IdAssigner worker.Type    `mapstructure:"id_assigner" json:"id_assigner" yaml:"id_assigner"` // local, db (bound by worker.Replace), cloudflare, env, net, redis, static or a registered name
+	Generator  generator.Type `mapstructure:"generator" json:"generator" yaml:"generator"`       // default, default_v2, cached or a registered name
 	TimeBits   int            `mapstructure:"time_bits" json:"time_bits" yaml:"time_bits"`       // (28 bits): 当前时间 -  "2016-05-20"的增量值, 单位: 秒
 	WorkerBits int            `mapstructure:"worker_bits" json:"worker_bits" yaml:"worker_bits"` // (22 bits): 机器 id, 最多可支持约 420w 次机器启动
 	SeqBits    int            `mapstructure:"seq_bits" json:"seq_bits" yaml:"seq_bits"`          // (13 bits): 每秒下的并发序列, 13 bits 可支持每秒 8192 个并发.
 	EpochStr   string         `mapstructure:"epoch_str" json:"epoch_str" yaml:"epoch_str"`       // "2016-05-20"
+	TimeUnit   Duration       `mapstructure:"time_unit" json:"time_unit" yaml:"time_unit"`       // 时间戳单位: 1s (默认), 100ms, 10ms, 1ms; 仅 default 与 default_v2 支持
+	JSSafe     bool           `mapstructure:"js_safe" json:"js_safe" yaml:"js_safe"`             // 要求 time+worker+seq 不超过 53 bits, UID 不超过 JavaScript Number.MAX_SAFE_INTEGER
+
+	ClockRollback    generator.RollbackPolicy `mapstructure:"clock_rollback" json:"clock_rollback" yaml:"clock_rollback"`             // reject, wait or borrow
+	MaxClockRollback Duration                 `mapstructure:"max_clock_rollback" json:"max_clock_rollback" yaml:"max_clock_rollback"` // largest backwards step waited out or borrowed over
+	MonotonicClock   bool                     `mapstructure:"monotonic_clock" json:"monotonic_clock" yaml:"monotonic_clock"`          // advance from a startup anchor by the monotonic clock
+
+	Checkpoint       bool     `mapstructure:"checkpoint" json:"checkpoint" yaml:"checkpoint"`                      // persist a high-water mark of issued timestamps per worker id
+	CheckpointDir    string   `mapstructure:"checkpoint_dir" json:"checkpoint_dir" yaml:"checkpoint_dir"`          // directory of the mark files, empty uses the assigner's store
+	CheckpointWindow Duration `mapstructure:"checkpoint_window" json:"checkpoint_window" yaml:"checkpoint_window"` // how far ahead each save reserves, default 5s
+	CheckpointWait   Duration `mapstructure:"checkpoint_wait" json:"checkpoint_wait" yaml:"checkpoint_wait"`       // wait this long beyond the window at startup for the clock to pass the mark, 0 refuses until it has
+
+	WorkerIdOverflow worker.OverflowPolicy `mapstructure:"worker_id_overflow" json:"worker_id_overflow" yaml:"worker_id_overflow"` // reject or wrap
+
+	IdAssignerChain []worker.Type `mapstructure:"id_assigner_chain" json:"id_assigner_chain" yaml:"id_assigner_chain"` // tried in order, overrides IdAssigner
+	AllowLocal      bool          `mapstructure:"allow_local" json:"allow_local" yaml:"allow_local"`                   // allow the chain to fall through to LocalWorkerId
+
+	DatacenterBits     int         `mapstructure:"datacenter_bits" json:"datacenter_bits" yaml:"datacenter_bits"`             // high worker bits used as datacenter id, 0 keeps a single worker id
+	DatacenterAssigner worker.Type `mapstructure:"datacenter_assigner" json:"datacenter_assigner" yaml:"datacenter_assigner"` // assigns the datacenter id, empty uses DatacenterId
+	DatacenterId       int64       `mapstructure:"datacenter_id" json:"datacenter_id" yaml:"datacenter_id"`                   // static datacenter id
+
+	VerifyInterval Duration `mapstructure:"verify_interval" json:"verify_interval" yaml:"verify_interval"` // re-verify worker id ownership this often, 0 disables
+}
+
+// Assigner returns the chain when IdAssignerChain is set, otherwise the IdAssigner instance. With
+// DatacenterBits set, that assigner provides the machine ID below the datacenter ID.
+func (c *Config) Assigner() (worker.IdAssigner, error) {
+	var assigner worker.IdAssigner
+	var err error
+	if len(c.IdAssignerChain) > 0 {
+		assigner, err = worker.NewChain(c.AllowLocal, c.IdAssignerChain...)
+	} else {
+		assigner, err = c.IdAssigner.Instance()
+	}
+	if err != nil || c.DatacenterBits == 0 {
+		return assigner, err
+	}
+
+	if c.DatacenterBits < 0 || c.DatacenterBits >= c.WorkerBits {
+		return nil, fmt.Errorf("datacenter bits %d must be in [0, %d)", c.DatacenterBits, c.WorkerBits)
+	}
+	var datacenter worker.IdAssigner = worker.Fixed(c.DatacenterId)
+	if c.DatacenterAssigner != "" {
+		if datacenter, err = c.DatacenterAssigner.Instance(); err != nil {
+			return nil, err
+		}
+	}
+	return worker.NewSplit(datacenter, assigner, c.WorkerBits-c.DatacenterBits), nil
+}
+
+// Duration is a time.Duration written as "1ms" or "5s" in configs; a bare number is nanoseconds.
+// Viper users decode it with mapstructure.TextUnmarshallerHookFunc.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = 0
+		return nil
+	}
+	if n, err := strconv.ParseInt(string(text), 10, 64); err == nil {
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	return utilu.UnmarshalJSONText(data, "duration", d)
 }
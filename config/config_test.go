@@ -0,0 +1,40 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestConfig_UnmarshalJSONDurations(t *testing.T) {
+	var conf Config
+	data := `{"time_unit": "1ms", "max_clock_rollback": "500ms", "checkpoint_window": "5s", "checkpoint_wait": "1m", "verify_interval": 30000000000}`
+	if err := json.Unmarshal([]byte(data), &conf); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][2]Duration{
+		"time_unit":          {conf.TimeUnit, Duration(time.Millisecond)},
+		"max_clock_rollback": {conf.MaxClockRollback, Duration(500 * time.Millisecond)},
+		"checkpoint_window":  {conf.CheckpointWindow, Duration(5 * time.Second)},
+		"checkpoint_wait":    {conf.CheckpointWait, Duration(time.Minute)},
+		"verify_interval":    {conf.VerifyInterval, Duration(30 * time.Second)},
+	}
+	for name, got := range want {
+		if got[0] != got[1] {
+			t.Errorf("%s = %s, want %s", name, got[0], got[1])
+		}
+	}
+
+	out, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Config
+	if err = json.Unmarshal(out, &again); err != nil || again.TimeUnit != conf.TimeUnit || again.CheckpointWait != conf.CheckpointWait {
+		t.Errorf("round trip of %s = %+v, %v", out, again, err)
+	}
+
+	if err = json.Unmarshal([]byte(`{"time_unit": "1 ms"}`), &conf); err == nil {
+		t.Error("Unmarshal() accepted a malformed duration")
+	}
+}
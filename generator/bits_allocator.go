@@ -2,6 +2,8 @@ package generator
 
 import (
 	"fmt"
+	"math"
+	"time"
 )
 
 const (
@@ -15,6 +17,9 @@ type BitsAllocator struct {
 	workerIdBits  int
 	sequenceBits  int
 
+	datacenterIdBits int           // high bits of the worker ID, 0 when the worker ID is not split
+	timeUnit         time.Duration // what one step of the timestamp field is worth, time.Second by default
+
 	maxDeltaSeconds int64
 	maxWorkerId     int64
 	maxSequence     int64
@@ -23,14 +28,9 @@ type BitsAllocator struct {
 	workerIdShift  int
 }
 
-// NewBitsAllocator creates a new BitsAllocator with the specified bit lengths
+// NewBitsAllocator creates a new BitsAllocator with the specified bit lengths, unchecked;
+// NewBitsAllocatorChecked and Layout.NewAllocator validate them
 func NewBitsAllocator(timestampBits, workerIdBits, sequenceBits int) *BitsAllocator {
-	// Ensure we allocate 64 bits
-	//totalBits := 1 + timestampBits + workerIdBits + sequenceBits
-	//if totalBits != TotalBits {
-	//	panic("Total bits do not add up to 64")
-	//}
-
 	maxDeltaSeconds := ^(-1 << uint(timestampBits))
 	maxWorkerId := ^(-1 << uint(workerIdBits))
 	maxSequence := ^(-1 << uint(sequenceBits))
@@ -48,7 +48,33 @@ func NewBitsAllocator(timestampBits, workerIdBits, sequenceBits int) *BitsAlloca
 		maxSequence:     int64(maxSequence),
 		timestampShift:  timestampShift,
 		workerIdShift:   workerIdShift,
+		timeUnit:        time.Second,
+	}
+}
+
+// NewBitsAllocatorChecked is NewBitsAllocator returning an error wrapping ErrInvalidLayout for bits ValidateBits rejects
+func NewBitsAllocatorChecked(timestampBits, workerIdBits, sequenceBits int) (*BitsAllocator, error) {
+	if err := ValidateBits(timestampBits, workerIdBits, sequenceBits); err != nil {
+		return nil, err
+	}
+	return NewBitsAllocator(timestampBits, workerIdBits, sequenceBits), nil
+}
+
+// ValidateBits checks every field has at least one bit and together they fit below the sign bit.
+func ValidateBits(timestampBits, workerIdBits, sequenceBits int) error {
+	for _, field := range []struct {
+		name string
+		bits int
+	}{{"timestamp", timestampBits}, {"worker id", workerIdBits}, {"sequence", sequenceBits}} {
+		if field.bits <= 0 || field.bits >= TotalBits {
+			return fmt.Errorf("%w: %s bits %d must be in [1, %d]", ErrInvalidLayout, field.name, field.bits, TotalBits-1)
+		}
+	}
+	if total := timestampBits + workerIdBits + sequenceBits; total > TotalBits-1 {
+		return fmt.Errorf("%w: %d timestamp + %d worker id + %d sequence bits make %d, the timestamp would shift %d bits into the sign bit",
+			ErrInvalidLayout, timestampBits, workerIdBits, sequenceBits, total, total-(TotalBits-1))
 	}
+	return nil
 }
 
 // Allocate combines the delta seconds, worker ID, and sequence into a single UID
@@ -56,6 +82,56 @@ func (b *BitsAllocator) Allocate(deltaSeconds, workerId, sequence int64) int64 {
 	return (deltaSeconds << uint(b.timestampShift)) | (workerId << uint(b.workerIdShift)) | sequence
 }
 
+// SplitWorkerIdBits splits the worker ID into a datacenter ID in its high datacenterIdBits and a
+// machine ID in the remaining bits, as in classic Snowflake. 0 undoes the split.
+func (b *BitsAllocator) SplitWorkerIdBits(datacenterIdBits int) error {
+	if datacenterIdBits < 0 || datacenterIdBits >= b.workerIdBits {
+		return fmt.Errorf("datacenter id bits %d must be in [0, %d)", datacenterIdBits, b.workerIdBits)
+	}
+	b.datacenterIdBits = datacenterIdBits
+	return nil
+}
+
+// ComposeWorkerId places datacenterId above machineId in the worker ID.
+func (b *BitsAllocator) ComposeWorkerId(datacenterId, machineId int64) (int64, error) {
+	if datacenterId < 0 || datacenterId > b.GetMaxDatacenterId() {
+		return 0, fmt.Errorf("datacenter id %d is outside [0, %d]", datacenterId, b.GetMaxDatacenterId())
+	}
+	if machineId < 0 || machineId > b.GetMaxMachineId() {
+		return 0, fmt.Errorf("machine id %d is outside [0, %d]", machineId, b.GetMaxMachineId())
+	}
+	return datacenterId<<uint(b.GetMachineIdBits()) | machineId, nil
+}
+
+// SplitWorkerId is the inverse of ComposeWorkerId.
+func (b *BitsAllocator) SplitWorkerId(workerId int64) (datacenterId, machineId int64) {
+	return workerId >> uint(b.GetMachineIdBits()), workerId & b.GetMaxMachineId()
+}
+
+// SetTimeUnit sets what one step of the timestamp field is worth, e.g. time.Millisecond for
+// Twitter-style layouts. The unit must divide a second; 0 means time.Second.
+func (b *BitsAllocator) SetTimeUnit(unit time.Duration) error {
+	if unit == 0 {
+		unit = time.Second
+	}
+	if unit < 0 || time.Second%unit != 0 {
+		return fmt.Errorf("time unit %s must divide a second", unit)
+	}
+	b.timeUnit = unit
+	return nil
+}
+
+// GetUnitsPerSecond returns how many time units make a second.
+func (b *BitsAllocator) GetUnitsPerSecond() int64 { return int64(time.Second / b.timeUnit) }
+
+// GetLifetime returns how long after the epoch the timestamp field runs out.
+func (b *BitsAllocator) GetLifetime() time.Duration {
+	if b.maxDeltaSeconds >= math.MaxInt64/int64(b.timeUnit) {
+		return math.MaxInt64
+	}
+	return time.Duration(b.maxDeltaSeconds+1) * b.timeUnit
+}
+
 // Getters for all the fields in BitsAllocator
 func (b *BitsAllocator) GetSignBits() int          { return b.signBits }
 func (b *BitsAllocator) GetTimestampBits() int     { return b.timestampBits }
@@ -67,6 +143,12 @@ func (b *BitsAllocator) GetMaxSequence() int64     { return b.maxSequence }
 func (b *BitsAllocator) GetTimestampShift() int    { return b.timestampShift }
 func (b *BitsAllocator) GetWorkerIdShift() int     { return b.workerIdShift }
 
+func (b *BitsAllocator) GetTimeUnit() time.Duration { return b.timeUnit }
+func (b *BitsAllocator) GetDatacenterIdBits() int   { return b.datacenterIdBits }
+func (b *BitsAllocator) GetMachineIdBits() int      { return b.workerIdBits - b.datacenterIdBits }
+func (b *BitsAllocator) GetMaxDatacenterId() int64  { return ^(-1 << uint(b.datacenterIdBits)) }
+func (b *BitsAllocator) GetMaxMachineId() int64     { return ^(-1 << uint(b.GetMachineIdBits())) }
+
 // String provides a string representation of BitsAllocator
 func (b *BitsAllocator) String() string {
 	return fmt.Sprintf("bitsAllocator{signBits: %d, timestampBits: %d, workerIdBits: %d, sequenceBits: %d, "+
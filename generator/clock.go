@@ -0,0 +1,132 @@
+package generator
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DefaultReconcileInterval = time.Minute
+	DefaultMaxSlew           = 30 * time.Millisecond // 500ppm of DefaultReconcileInterval, the rate NTP slews at
+)
+
+// Clock is the time source of the generators.
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// SystemClock reads the wall clock through time.Now.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// MonotonicClock reads the wall clock once and from then on advances by Go's monotonic clock, so
+// NTP steps and wall clock resets never move it backwards. Every ReconcileInterval it slews towards
+// the wall clock by at most MaxSlew, and never below a time it has already reported.
+//
+// The monotonic clock may stop while the host is suspended; the clock then lags the wall clock
+// and only catches up at the slew rate.
+type MonotonicClock struct {
+	ReconcileInterval time.Duration // defaults to DefaultReconcileInterval
+	MaxSlew           time.Duration // largest correction per reconcile, defaults to DefaultMaxSlew
+
+	// Wall reads the wall clock to reconcile with, defaults to time.Now.
+	Wall func() time.Time
+
+	mu         sync.Mutex
+	anchor     time.Time // carries the monotonic reading
+	wallAnchor time.Time
+	offset     time.Duration
+	reconciled time.Time
+	last       time.Time
+	drift      time.Duration
+}
+
+func NewMonotonicClock(reconcileInterval, maxSlew time.Duration) *MonotonicClock {
+	return &MonotonicClock{ReconcileInterval: reconcileInterval, MaxSlew: maxSlew}
+}
+
+func (c *MonotonicClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	mono := time.Now()
+	if c.anchor.IsZero() {
+		c.anchor, c.wallAnchor, c.reconciled = mono, c.wall().Round(0), mono
+	}
+	now := c.wallAnchor.Add(mono.Sub(c.anchor) + c.offset)
+
+	interval := c.ReconcileInterval
+	if interval <= 0 {
+		interval = DefaultReconcileInterval
+	}
+	if mono.Sub(c.reconciled) >= interval {
+		maxSlew := c.MaxSlew
+		if maxSlew <= 0 {
+			maxSlew = DefaultMaxSlew
+		}
+		c.drift = c.wall().Round(0).Sub(now)
+		c.offset += min(max(c.drift, -maxSlew), maxSlew)
+		c.reconciled = mono
+		now = c.wallAnchor.Add(mono.Sub(c.anchor) + c.offset)
+	}
+
+	if now.Before(c.last) {
+		now = c.last
+	}
+	c.last = now
+	return now
+}
+
+func (c *MonotonicClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Drift returns how far the wall clock was ahead of the clock at the last reconcile, before slewing.
+func (c *MonotonicClock) Drift() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.drift
+}
+
+func (c *MonotonicClock) wall() time.Time {
+	if c.Wall != nil {
+		return c.Wall()
+	}
+	return time.Now()
+}
+
+// ManualClock is a clock moved only by hand, for deterministic tests. Sleep advances it instead of
+// blocking, so waits for the next tick or out of a rollback return at once.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Sleep(d time.Duration) { c.Add(d) }
+
+// Set moves the clock to now, backwards as well as forwards.
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Add moves the clock by d, which may be negative.
+func (c *ManualClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
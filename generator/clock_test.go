@@ -0,0 +1,56 @@
+package generator
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type steppedWall struct {
+	mu   sync.Mutex
+	step time.Duration
+}
+
+func (w *steppedWall) Now() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return time.Now().Add(w.step)
+}
+
+func (w *steppedWall) Step(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.step += d
+}
+
+func TestMonotonicClock(t *testing.T) {
+	wall := &steppedWall{}
+	clock := NewMonotonicClock(time.Nanosecond, time.Millisecond)
+	clock.Wall = wall.Now
+
+	start := clock.Now()
+	wall.Step(-time.Hour)
+
+	last := start
+	for i := 0; i < 100; i++ {
+		now := clock.Now()
+		if now.Before(last) {
+			t.Fatalf("Now() = %s went back from %s", now, last)
+		}
+		last = now
+	}
+	if drift := clock.Drift(); drift > -59*time.Minute {
+		t.Errorf("Drift() = %s, want about -1h", drift)
+	}
+	// at most 1ms of slew per reconcile, and it never went back, so the clock froze instead
+	if elapsed := last.Sub(start); elapsed > time.Second {
+		t.Errorf("clock moved %s after 100 reads", elapsed)
+	}
+
+	wall.Step(time.Hour + 50*time.Millisecond)
+	offset := clock.offset
+	clock.Now()
+	if slew := clock.offset - offset; slew != time.Millisecond {
+		t.Errorf("slewed %s towards a wall clock ahead, want MaxSlew 1ms", slew)
+	}
+}
@@ -1,12 +1,130 @@
 package generator
 
-type Type uint
+import (
+	"errors"
+	"fmt"
+	"github.com/gomsr/atom-uid/utilu"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Type names a registered generator kind, e.g. "default" or "cached".
+type Type string
 
 const (
-	DefaultUid Type = iota
-	CachedUid
+	DefaultUid   Type = "default"
+	DefaultUidV2 Type = "default_v2"
+	CachedUid    Type = "cached"
 )
 
+// legacyTypes are the types named by the former uint constants, still found in numeric configs, indexed by value.
+var legacyTypes = []Type{DefaultUid, CachedUid}
+
+// Normalize lower-cases the name and resolves the former numeric values. The empty type is DefaultUid.
+func (t Type) Normalize() Type {
+	if i, ok := utilu.LookupName(legacyTypes, string(t)); ok {
+		return legacyTypes[i]
+	}
+	name := strings.ToLower(strings.TrimSpace(string(t)))
+	if name == "" {
+		return DefaultUid
+	}
+	return Type(name)
+}
+
+func (t *Type) UnmarshalText(text []byte) error {
+	*t = Type(text).Normalize()
+	return nil
+}
+
+// UnmarshalJSON also accepts the former numeric values.
+func (t *Type) UnmarshalJSON(data []byte) error {
+	return utilu.UnmarshalJSONText(data, "generator type", t)
+}
+
+// ErrClosed is returned by generators after Close released their worker ID.
+var ErrClosed = errors.New("generator is closed")
+
+// OwnershipLostError is returned by generators whose watchdog found the worker ID is no longer theirs.
+// The generator refuses to issue from then on, since another node may be issuing with the same ID.
+type OwnershipLostError struct {
+	WorkerId int64
+	Err      error
+}
+
+func (e *OwnershipLostError) Error() string {
+	return fmt.Sprintf("worker id %d ownership lost, refusing UID generation: %v", e.WorkerId, e.Err)
+}
+
+func (e *OwnershipLostError) Unwrap() error { return e.Err }
+
+// RollbackPolicy decides what a generator does when the clock steps back behind its last timestamp.
+type RollbackPolicy uint
+
+const (
+	// RejectRollback fails at once, whatever the drift.
+	RejectRollback RollbackPolicy = iota
+	// WaitRollback sleeps until the clock catches up, if the drift is within the tolerance.
+	WaitRollback
+	// BorrowRollback keeps issuing from the last timestamp with the spare sequence space, then
+	// from the following ones, as long as that stays within the tolerance ahead of the clock.
+	BorrowRollback
+)
+
+// rollbackPolicies are the config names of the policies, indexed by value.
+var rollbackPolicies = []string{"reject", "wait", "borrow"}
+
+// UnmarshalText accepts a policy name, or its former numeric value. Empty text is RejectRollback.
+func (p *RollbackPolicy) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		*p = RejectRollback
+		return nil
+	}
+	if i, ok := utilu.LookupName(rollbackPolicies, string(text)); ok {
+		*p = RollbackPolicy(i)
+		return nil
+	}
+	return fmt.Errorf("unknown clock rollback policy %q, want one of %s", text, strings.Join(rollbackPolicies, ", "))
+}
+
+// UnmarshalJSON also accepts the former numeric values.
+func (p *RollbackPolicy) UnmarshalJSON(data []byte) error {
+	return utilu.UnmarshalJSONText(data, "clock rollback policy", p)
+}
+
+// String returns the config name of the policy.
+func (p RollbackPolicy) String() string {
+	if int(p) < len(rollbackPolicies) {
+		return rollbackPolicies[p]
+	}
+	return strconv.FormatUint(uint64(p), 10)
+}
+
+// MarshalText writes the policy name, so configs round-trip through UnmarshalText.
+func (p RollbackPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// ClockRollbackError is returned when the clock moved backwards by more than the tolerated drift.
+type ClockRollbackError struct {
+	Drift     time.Duration
+	Tolerance time.Duration
+}
+
+func (e *ClockRollbackError) Error() string {
+	return fmt.Sprintf("clock moved backwards by %s, tolerance %s. Refusing UID generation", e.Drift, e.Tolerance)
+}
+
+// UIDRange is a run of consecutive UIDs from First to Last inclusive, all in one time unit.
+type UIDRange struct {
+	First int64
+	Last  int64
+}
+
+// Len returns how many UIDs the range holds.
+func (r UIDRange) Len() int64 { return r.Last - r.First + 1 }
+
 const (
 	EpochStr       = "2024-01-01"
 	EpochStrFormat = "2006-01-02"
@@ -21,6 +139,6 @@ type UidGenerator interface {
 	GetUID() (int64, error)
 
 	// ParseUID parses the given UID into its components (e.g., timestamp, worker ID, sequence).
-	// Returns the parsed information as a string.
+	// Returns the parsed information as a JSON encoded UIDInfo.
 	ParseUID(uid int64) string
 }
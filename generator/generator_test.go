@@ -0,0 +1,33 @@
+package generator
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestRollbackPolicy_UnmarshalJSON(t *testing.T) {
+	var conf struct {
+		Policies []RollbackPolicy `json:"policies"`
+	}
+	if err := json.Unmarshal([]byte(`{"policies": ["Borrow", "wait", "", 1, "0"]}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	want := []RollbackPolicy{BorrowRollback, WaitRollback, RejectRollback, WaitRollback, RejectRollback}
+	for i, p := range conf.Policies {
+		if p != want[i] {
+			t.Errorf("policies[%d] = %d, want %d", i, p, want[i])
+		}
+	}
+
+	for _, data := range []string{`"skew"`, `3`, `true`} {
+		var p RollbackPolicy
+		if err := json.Unmarshal([]byte(data), &p); err == nil {
+			t.Errorf("Unmarshal(%s) = %d, want an error", data, p)
+		}
+	}
+
+	data, _ := json.Marshal(conf)
+	if string(data) != `{"policies":["borrow","wait","reject","wait","reject"]}` {
+		t.Errorf("marshalled %s", data)
+	}
+}
@@ -0,0 +1,40 @@
+package generators
+
+import (
+	"fmt"
+	"github.com/gomsr/atom-uid/generator"
+)
+
+// MaxBatchSize bounds GetUIDs and NextRange, so a bad n cannot exhaust memory or the timestamp bits
+const MaxBatchSize = 1 << 20
+
+func checkBatchSize(n int) error {
+	if n <= 0 || n > MaxBatchSize {
+		return fmt.Errorf("batch size %d must be in [1, %d]", n, MaxBatchSize)
+	}
+	return nil
+}
+
+// expandRanges lists the n UIDs of ranges
+func expandRanges(ranges []generator.UIDRange, n int) []int64 {
+	uids := make([]int64, 0, n)
+	for _, r := range ranges {
+		for uid := r.First; uid <= r.Last; uid++ {
+			uids = append(uids, uid)
+		}
+	}
+	return uids
+}
+
+// collectRanges folds runs of consecutive UIDs into ranges
+func collectRanges(uids []int64) []generator.UIDRange {
+	var ranges []generator.UIDRange
+	for _, uid := range uids {
+		if last := len(ranges) - 1; last >= 0 && ranges[last].Last+1 == uid {
+			ranges[last].Last = uid
+			continue
+		}
+		ranges = append(ranges, generator.UIDRange{First: uid, Last: uid})
+	}
+	return ranges
+}
@@ -2,6 +2,7 @@ package buffer
 
 import (
 	"fmt"
+	"github.com/gomsr/atom-uid/generator"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -30,6 +31,17 @@ type SchedulePaddingExecutor struct {
 }
 
 func NewBufferPaddingExecutor(ringBuffer *RingBuffer, uidProvider UidProvider, epochSeconds int64, interval time.Duration) *SchedulePaddingExecutor {
+	return NewBufferPaddingExecutorAfter(ringBuffer, uidProvider, epochSeconds, 0, interval)
+}
+
+// NewBufferPaddingExecutorAfter pads from the later of now and afterSecond, so no UID at or below afterSecond is produced
+func NewBufferPaddingExecutorAfter(ringBuffer *RingBuffer, uidProvider UidProvider, epochSeconds, afterSecond int64, interval time.Duration) *SchedulePaddingExecutor {
+	return NewBufferPaddingExecutorClock(ringBuffer, uidProvider, epochSeconds, afterSecond, interval, generator.SystemClock)
+}
+
+// NewBufferPaddingExecutorClock is NewBufferPaddingExecutorAfter reading the current second from clock
+func NewBufferPaddingExecutorClock(ringBuffer *RingBuffer, uidProvider UidProvider, epochSeconds, afterSecond int64,
+	interval time.Duration, clock generator.Clock) *SchedulePaddingExecutor {
 	executor := &SchedulePaddingExecutor{
 		epochSeconds:        epochSeconds,
 		ringBuffer:          ringBuffer,
@@ -38,7 +50,7 @@ func NewBufferPaddingExecutor(ringBuffer *RingBuffer, uidProvider UidProvider, e
 		stopPaddingSchedule: make(chan struct{}),
 	}
 
-	executor.lastSecond.Store(time.Now().Unix())
+	executor.lastSecond.Store(max(clock.Now().Unix(), afterSecond))
 	if interval > 0 {
 		executor.bufferPadSchedule = time.NewTicker(interval)
 		go executor.StartSchedule()
@@ -91,6 +103,11 @@ func (e *SchedulePaddingExecutor) PaddingBuffer() {
 	fmt.Printf("End to padding buffer lastSecond: %d", e.lastSecond.Load())
 }
 
+// LastSecond returns the last second padded into the ring buffer
+func (e *SchedulePaddingExecutor) LastSecond() int64 {
+	return e.lastSecond.Load()
+}
+
 func (e *SchedulePaddingExecutor) Shutdown() {
 	if e.bufferPadSchedule != nil {
 		close(e.stopPaddingSchedule)
@@ -132,6 +132,44 @@ func (rb *RingBuffer) Take() (int64, error) {
 	return uid, nil
 }
 
+// TakeN takes the next n UIDs in one step, moving the cursor past all of them at once, so the run
+// is contiguous in the ring and no concurrent Take lands inside it
+func (rb *RingBuffer) TakeN(n int) ([]int64, error) {
+	if n <= 0 || n >= rb.bufferSize {
+		return nil, fmt.Errorf("take %d must be in [1, %d)", n, rb.bufferSize)
+	}
+
+	var currentCursor, lastCursor, currentTail int64
+	for {
+		currentCursor, currentTail = rb.cursor.Load(), rb.tail.Load()
+		lastCursor = currentCursor + int64(n)
+		if lastCursor >= currentTail {
+			rb.rejectedTakeHandler.rejectTakeBuffer(rb)
+			return nil, fmt.Errorf("take %d: only %d uids are padded", n, max(currentTail-currentCursor-1, 0))
+		}
+		if rb.cursor.CompareAndSwap(currentCursor, lastCursor) {
+			break
+		}
+	}
+
+	// 异步填充逻辑
+	if currentTail-lastCursor < int64(rb.paddingThreshold) {
+		rb.bufferPaddingExecutor.AsyncPadding()
+	}
+
+	uids := make([]int64, 0, n)
+	for cursor := currentCursor + 1; cursor <= lastCursor; cursor++ {
+		index := cursor & rb.indexMask
+		uid := rb.slots[index] // must before swap
+		if !atomic.CompareAndSwapInt32(&rb.flags[index], CanTakeFlag, CanPutFlag) {
+			rb.rejectedTakeHandler.rejectTakeBuffer(rb)
+			return nil, errors.New("cursor not in can take status")
+		}
+		uids = append(uids, uid)
+	}
+	return uids, nil
+}
+
 // SetRejectedPutHandler sets the handler for rejected put operations
 func (rb *RingBuffer) SetRejectedPutHandler(handler RejectedPutHandler) {
 	rb.rejectedPutHandler = handler
@@ -8,12 +8,13 @@ type UidProvider interface {
 	provide(epochSeconds, momentInSecond int64) []int64
 }
 
-func NewCachedUidProvider(g *generator.BitsAllocator) *CachedUidProvider {
-	return &CachedUidProvider{g}
+func NewCachedUidProvider(g *generator.BitsAllocator, workerId int64) *CachedUidProvider {
+	return &CachedUidProvider{g, workerId}
 }
 
 type CachedUidProvider struct {
 	*generator.BitsAllocator
+	workerId int64
 }
 
 // NextIdsForOneSecond Get the UIDs in the same specified second under the max sequence
@@ -21,7 +22,7 @@ func (c *CachedUidProvider) provide(epochSeconds, currentSecond int64) []int64 {
 	listSize := c.GetMaxSequence() + 1
 	uidList := make([]int64, listSize)
 
-	firstSeqUid := c.Allocate(currentSecond-epochSeconds, c.GetMaxWorkerId(), 0)
+	firstSeqUid := c.Allocate(currentSecond-epochSeconds, c.workerId, 0)
 	for offset := int64(0); offset < listSize; offset++ {
 		uidList[offset] = firstSeqUid + offset
 	}
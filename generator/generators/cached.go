@@ -1,10 +1,15 @@
 package generators
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"github.com/gomsr/atom-uid/config"
 	"github.com/gomsr/atom-uid/generator"
 	"github.com/gomsr/atom-uid/generator/generators/buffer"
+	"github.com/gomsr/atom-uid/worker"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,52 +20,104 @@ const (
 )
 
 type CachedUidGenerator struct {
-	timeBits      int
-	workerBits    int
-	seqBits       int
-	epochStr      string
-	epochSeconds  int64
-	bitsAllocator *generator.BitsAllocator
-	workerId      int64
-	sequence      int64
-	lastSecond    int64
-	mu            sync.Mutex
-
-	boostPower    int
-	paddingFactor int
-	ringBuffer    *buffer.RingBuffer
+	timeBits   int
+	workerBits int
+	seqBits    int
+	epochStr   string
+	codec
+	workerId   int64
+	sequence   int64
+	lastSecond int64
+	mu         sync.Mutex
+
+	boostPower      int
+	paddingFactor   int
+	ringBuffer      *buffer.RingBuffer
+	paddingExecutor *buffer.SchedulePaddingExecutor
+	assigner        worker.IdAssigner
+	watchdog        *watchdog
+	closed          atomic.Bool
 }
 
-func NewCached(workerId int64) *CachedUidGenerator {
+func NewCached(workerId int64) (*CachedUidGenerator, error) {
 	return NewCachedUidGenerator(28, 15, 20,
 		BoostPower, PaddingFactor, ScheduleInterval, workerId)
 }
 
+// NewCachedWithConfigContext builds a CachedUidGenerator with the configured layout and assigner
+func NewCachedWithConfigContext(ctx context.Context, conf *config.Config) (*CachedUidGenerator, error) {
+	if conf == nil {
+		return nil, errors.New("config is nil")
+	}
+
+	if conf.TimeUnit != 0 && time.Duration(conf.TimeUnit) != time.Second {
+		return nil, fmt.Errorf("cached generator counts in seconds, time unit %s is not supported", conf.TimeUnit)
+	}
+	if conf.Checkpoint || conf.CheckpointDir != "" || conf.CheckpointWindow != 0 || conf.CheckpointWait != 0 {
+		return nil, errors.New("cached generator does not support checkpoints")
+	}
+	if _, err := layoutWithConfig(conf); err != nil {
+		return nil, err
+	}
+
+	assigner, wid, err := assignWorkerIdWithConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	gtor, err := newCachedUidGenerator(clockWithConfig(conf), conf.TimeBits, conf.WorkerBits, conf.SeqBits,
+		BoostPower, PaddingFactor, ScheduleInterval, wid, assigner, conf.EpochStr)
+	if err != nil {
+		return nil, releaseAssigned(assigner, wid, err)
+	}
+	if err = gtor.bitsAllocator.SplitWorkerIdBits(conf.DatacenterBits); err != nil {
+		gtor.paddingExecutor.Shutdown()
+		return nil, releaseAssigned(assigner, wid, err)
+	}
+	gtor.watchdog = startWatchdog(assigner, wid, time.Duration(conf.VerifyInterval))
+	return gtor, nil
+}
+
 func NewCachedUidGenerator(timeBits, workerBits, seqBits, boostPower, paddingFactor int,
-	scheduleInterval time.Duration, workerId int64, epochStr ...string) *CachedUidGenerator {
+	scheduleInterval time.Duration, workerId int64, epochStr ...string) (*CachedUidGenerator, error) {
+	return newCachedUidGenerator(generator.SystemClock, timeBits, workerBits, seqBits, boostPower, paddingFactor,
+		scheduleInterval, workerId, nil, epochStr...)
+}
+
+// NewCachedUidGeneratorClock is NewCachedUidGenerator padding from the current second of clock
+func NewCachedUidGeneratorClock(clock generator.Clock, timeBits, workerBits, seqBits, boostPower, paddingFactor int,
+	scheduleInterval time.Duration, workerId int64, epochStr ...string) (*CachedUidGenerator, error) {
+	if clock == nil {
+		clock = generator.SystemClock
+	}
+	return newCachedUidGenerator(clock, timeBits, workerBits, seqBits, boostPower, paddingFactor,
+		scheduleInterval, workerId, nil, epochStr...)
+}
+
+func newCachedUidGenerator(clock generator.Clock, timeBits, workerBits, seqBits, boostPower, paddingFactor int,
+	scheduleInterval time.Duration, workerId int64, assigner worker.IdAssigner, epochStr ...string) (*CachedUidGenerator, error) {
+	epoch, dt, err := parseEpoch(epochStr...)
+	if err != nil {
+		return nil, err
+	}
+	layout := generator.Layout{TimestampBits: timeBits, WorkerIdBits: workerBits, SequenceBits: seqBits, Epoch: dt}
+	allocator, err := layout.NewAllocator(clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err = worker.RejectOverflow.Apply(workerId, allocator.GetMaxWorkerId()); err != nil {
+		return nil, err
+	}
+
 	gtor := &CachedUidGenerator{
 		timeBits:      timeBits,
 		workerBits:    workerBits,
 		seqBits:       seqBits,
-		bitsAllocator: generator.NewBitsAllocator(timeBits, workerBits, seqBits),
+		codec:         codec{epochSeconds: dt.Unix(), bitsAllocator: allocator},
 		workerId:      workerId,
 		boostPower:    boostPower,
 		paddingFactor: paddingFactor,
-	}
-	// 2. 处理 epochStr
-	if len(epochStr) == 0 {
-		gtor.epochStr = generator.EpochStr
-		dt, _ := time.Parse(generator.EpochStrFormat, generator.EpochStr)
-		gtor.epochSeconds = dt.Unix()
-	} else {
-		if parse, err := time.Parse(generator.EpochStrFormat, epochStr[0]); err != nil {
-			gtor.epochStr = generator.EpochStr
-			dt, _ := time.Parse(generator.EpochStrFormat, generator.EpochStr)
-			gtor.epochSeconds = dt.Unix()
-		} else {
-			gtor.epochStr = epochStr[0]
-			gtor.epochSeconds = parse.Unix()
-		}
+		assigner:      assigner,
+		epochStr:      epoch,
 	}
 
 	// 3. 创建 ringBuffer & 设置拒绝策略 & executor
@@ -68,22 +125,33 @@ func NewCachedUidGenerator(timeBits, workerBits, seqBits, boostPower, paddingFac
 	ringBuffer := buffer.NewBuffer(int(bufferSize), gtor.paddingFactor)
 	fmt.Printf("Initialized ring buffer size: %d, paddingFactor: %d\n", bufferSize, gtor.paddingFactor)
 
-	// 4. 创建 PaddingExecutor
-	paddingExecutor := buffer.NewBufferPaddingExecutor(ringBuffer,
-		buffer.NewCachedUidProvider(gtor.bitsAllocator), gtor.epochSeconds, scheduleInterval)
+	// 4. 创建 PaddingExecutor, 从上一个 owner 释放时的 timestamp 之后开始填充
+	var fence int64
+	if assigner != nil {
+		fence = worker.Fence(assigner)
+	}
+	paddingExecutor := buffer.NewBufferPaddingExecutorClock(ringBuffer,
+		buffer.NewCachedUidProvider(gtor.bitsAllocator, gtor.workerId), gtor.epochSeconds, fence, scheduleInterval, clock)
 	ringBuffer.SetBufferPaddingExecutor(paddingExecutor)
 	fmt.Printf("Initialized BufferPaddingExecutor. Using schedule: %v, interval: %v\n", scheduleInterval > 0, scheduleInterval)
 
 	gtor.ringBuffer = ringBuffer
-	return gtor
+	gtor.paddingExecutor = paddingExecutor
+	return gtor, nil
 }
 
 func (g *CachedUidGenerator) GetUID() (int64, error) {
+	if g.closed.Load() {
+		return 0, generator.ErrClosed
+	}
+	if err := g.watchdog.err(); err != nil {
+		return 0, err
+	}
 	return g.ringBuffer.Take()
 }
 
 func (g *CachedUidGenerator) MustUID() int64 {
-	take, err := g.ringBuffer.Take()
+	take, err := g.GetUID()
 	if err != nil {
 		panic(err)
 	}
@@ -91,22 +159,42 @@ func (g *CachedUidGenerator) MustUID() int64 {
 	return take
 }
 
-func (g *CachedUidGenerator) ParseUID(uid int64) string {
-	totalBits := generator.TotalBits
-	signBits := g.bitsAllocator.GetSignBits()
-	timestampBits := g.bitsAllocator.GetTimestampBits()
-	workerIdBits := g.bitsAllocator.GetWorkerIdBits()
-	sequenceBits := g.bitsAllocator.GetSequenceBits()
-
-	// Parse UID
-	sequence := (uid << uint(totalBits-sequenceBits)) >> uint(totalBits-sequenceBits)
-	workerId := (uid << uint(timestampBits+signBits)) >> uint(totalBits-workerIdBits)
-	deltaSeconds := uid >> uint(workerIdBits+sequenceBits)
-
-	// Format time from epoch
-	thatTime := time.Unix(g.epochSeconds+deltaSeconds, 0)
-	return fmt.Sprintf("{\"UID\":\"%d\",\"timestamp\":\"%s\",\"workerId\":\"%d\",\"sequence\":\"%d\"}",
-		uid, thatTime.Format("2006-01-02 15:04:05"), workerId, sequence)
+// GetUIDs takes a contiguous run of n unique IDs from the ring buffer in one step. The run cannot
+// be longer than what is padded, at most the ring buffer size minus one; larger batches fail
+func (g *CachedUidGenerator) GetUIDs(n int) ([]int64, error) {
+	if err := checkBatchSize(n); err != nil {
+		return nil, err
+	}
+	if g.closed.Load() {
+		return nil, generator.ErrClosed
+	}
+	if err := g.watchdog.err(); err != nil {
+		return nil, err
+	}
+	return g.ringBuffer.TakeN(n)
+}
+
+// NextRange is GetUIDs with runs of consecutive IDs folded into ranges
+func (g *CachedUidGenerator) NextRange(n int) ([]generator.UIDRange, error) {
+	uids, err := g.GetUIDs(n)
+	if err != nil {
+		return nil, err
+	}
+	return collectRanges(uids), nil
+}
+
+// Close stops the padding executor and releases the worker ID through its assigner with the
+// last padded second, which bounds every UID handed out; afterwards GetUID fails with generator.ErrClosed
+func (g *CachedUidGenerator) Close() error {
+	if !g.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	g.watchdog.close()
+	g.paddingExecutor.Shutdown()
+	if g.assigner == nil {
+		return nil
+	}
+	return worker.Release(context.Background(), g.assigner, g.workerId, g.paddingExecutor.LastSecond())
 }
 
 func (g *CachedUidGenerator) SetBoostPower(boostPower int) {
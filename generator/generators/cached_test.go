@@ -1,14 +1,27 @@
 package generators
 
 import (
+	"context"
 	"fmt"
+	"github.com/gomsr/atom-uid/generator"
 	"github.com/gomsr/atom-uid/worker"
 	"testing"
 	"time"
 )
 
 func TestCachedUidGenerator_GetUID(t *testing.T) {
-	g := NewCached(worker.LocalWorkerId.Instance().NextWorkerId())
+	assigner, err := worker.LocalWorkerId.Instance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wid, err := assigner.NextWorkerIdContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := NewCached(wid)
+	if err != nil {
+		t.Fatal(err)
+	}
 	for i := 0; i < 1000; i++ {
 		uid, err := g.GetUID()
 		time.Sleep(100 * time.Millisecond)
@@ -21,6 +34,19 @@ func TestCachedUidGenerator_GetUID(t *testing.T) {
 }
 
 func TestParse(t *testing.T) {
-	g := NewCached(worker.LocalWorkerId.Instance().NextWorkerId())
-	print(g.ParseUID(1132079780664967169))
+	// The layout of NewCached, decoded without building a generator
+	layout, err := generator.NewLayout(28, 15, 20, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := layout.Decode(1132079780664967169)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 1, 16, 8, 10, 57, 0, time.UTC); !info.Time.Equal(want) || info.WorkerId != 32767 || info.Sequence != 1 {
+		t.Errorf("Decode() = %s, want worker 32767 sequence 1 at %s", info, want)
+	}
+	if uid, err := layout.Compose(info.Time, info.WorkerId, info.Sequence); err != nil || uid != info.UID {
+		t.Errorf("Compose() = %d, %v, want %d", uid, err, info.UID)
+	}
 }
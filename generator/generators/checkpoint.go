@@ -0,0 +1,109 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomsr/atom-uid/config"
+	"github.com/gomsr/atom-uid/generator"
+	"github.com/gomsr/atom-uid/worker"
+	"github.com/gomsr/atom-uid/worker/workers"
+	"time"
+)
+
+const (
+	DefaultCheckpointWindow = 5 * time.Second
+)
+
+// highWater reserves timestamps a window ahead in a worker.Checkpoint before issuing with them,
+// so a restart with the same worker ID never issues at or below a timestamp already used
+type highWater struct {
+	store    worker.Checkpoint
+	workerId int64
+	window   time.Duration
+	mark     int64 // unix milliseconds, above every timestamp issued
+}
+
+// checkpointWithConfig returns the configured checkpoint, nil when disabled
+func checkpointWithConfig(conf *config.Config, assigner worker.IdAssigner) (worker.Checkpoint, error) {
+	if !conf.Checkpoint {
+		return nil, nil
+	}
+	if conf.CheckpointDir != "" {
+		return workers.NewFileCheckpoint(conf.CheckpointDir), nil
+	}
+	if store, ok := assigner.(worker.Checkpoint); ok {
+		return store, nil
+	}
+	return nil, errors.New("checkpoint: set CheckpointDir, the assigner cannot keep a high-water mark")
+}
+
+// openHighWater loads the mark of workerId. A mark at most window and one unit ahead of the clock is a
+// reservation left by a stop without Close, which is waited out. If the clock is behind it by more, it
+// waits up to wait on top for the clock to pass it; with wait 0 the generator refuses to issue until it has.
+func openHighWater(ctx context.Context, store worker.Checkpoint, workerId int64, window, wait, unit time.Duration,
+	clock generator.Clock) (*highWater, error) {
+	mark, err := store.LoadHighWater(ctx, workerId)
+	if err != nil {
+		return nil, fmt.Errorf("load high-water mark: %w", err)
+	}
+	if window <= 0 {
+		window = DefaultCheckpointWindow
+	}
+
+	reserved := window + unit
+	if behind := time.UnixMilli(mark).Sub(clock.Now()); behind > 0 && (behind <= reserved || wait > 0) {
+		if behind > reserved+wait {
+			return nil, fmt.Errorf("high-water mark of worker id %d: %w", workerId,
+				&generator.ClockRollbackError{Drift: behind, Tolerance: reserved + wait})
+		}
+		clock.Sleep(behind)
+	}
+	return &highWater{store: store, workerId: workerId, window: window, mark: mark}, nil
+}
+
+// lastTick returns the last tick in unit below the mark, the generator must issue after it
+func (h *highWater) lastTick(unit time.Duration) int64 {
+	return (h.mark*int64(time.Millisecond)+int64(unit)-1)/int64(unit) - 1
+}
+
+// reserve saves a new mark a window ahead when tick reaches the current one
+func (h *highWater) reserve(tick int64, unit time.Duration) error {
+	if h == nil {
+		return nil
+	}
+	end := tickEnd(tick, unit)
+	if end <= h.mark {
+		return nil
+	}
+
+	mark := end + h.window.Milliseconds()
+	if err := h.store.SaveHighWater(context.Background(), h.workerId, mark); err != nil {
+		return fmt.Errorf("save high-water mark: %w", err)
+	}
+	h.mark = mark
+	return nil
+}
+
+// settle lowers the mark to just above tick, the last one issued, so a restart after Close need not
+// wait out the reservation
+func (h *highWater) settle(tick int64, unit time.Duration) error {
+	if h == nil {
+		return nil
+	}
+	end := tickEnd(tick, unit)
+	if end >= h.mark {
+		return nil
+	}
+
+	if err := h.store.SaveHighWater(context.Background(), h.workerId, end); err != nil {
+		return fmt.Errorf("save high-water mark: %w", err)
+	}
+	h.mark = end
+	return nil
+}
+
+// tickEnd returns the unix millisecond the tick in unit ends at, rounded up
+func tickEnd(tick int64, unit time.Duration) int64 {
+	return ((tick+1)*int64(unit) + int64(time.Millisecond) - 1) / int64(time.Millisecond)
+}
@@ -0,0 +1,192 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomsr/atom-uid/generator"
+	"github.com/gomsr/atom-uid/worker"
+	"sync"
+)
+
+// codec decodes the UIDs of a layout; every generator embeds it
+type codec struct {
+	epochSeconds  int64
+	bitsAllocator *generator.BitsAllocator
+}
+
+// core is the issuing state and logic DefaultUidGenerator and DefaultUidGeneratorV2 share
+type core struct {
+	codec
+	workerId   int64
+	sequence   int64
+	lastSecond int64
+	assigner   worker.IdAssigner
+	watchdog   *watchdog
+	highWater  *highWater
+	rollback   rollbackGuard
+	clock      generator.Clock
+	closed     bool
+	mu         sync.Mutex
+}
+
+// GetUID generates a unique ID
+func (g *core) GetUID() (int64, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.nextId()
+}
+
+// MustUID generates a unique ID
+func (g *core) MustUID() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id, err := g.nextId()
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+// GetUIDs generates n unique IDs under one lock acquisition
+func (g *core) GetUIDs(n int) ([]int64, error) {
+	ranges, err := g.NextRange(n)
+	if err != nil {
+		return nil, err
+	}
+	return expandRanges(ranges, n), nil
+}
+
+// NextRange reserves n unique IDs under one lock acquisition, as runs of consecutive IDs per time
+// unit; once a unit's sequence space is used up the reservation continues into the next units
+func (g *core) NextRange(n int) ([]generator.UIDRange, error) {
+	if err := checkBatchSize(n); err != nil {
+		return nil, err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	var ranges []generator.UIDRange
+	for rest := int64(n); rest > 0; {
+		first, err := g.nextId()
+		if err != nil {
+			return nil, err
+		}
+		more := min(rest-1, g.bitsAllocator.GetMaxSequence()-g.sequence)
+		g.sequence += more
+		ranges = append(ranges, generator.UIDRange{First: first, Last: first + more})
+		rest -= more + 1
+	}
+	return ranges, nil
+}
+
+// Close settles the high-water mark at the last issued tick and releases the worker ID through its
+// assigner with the last issued second; afterwards GetUID fails with generator.ErrClosed
+func (g *core) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return nil
+	}
+	g.closed = true
+	g.watchdog.close()
+	err := g.highWater.settle(g.lastSecond, g.bitsAllocator.GetTimeUnit())
+	if g.assigner == nil {
+		return err
+	}
+	return errors.Join(err, worker.Release(context.Background(), g.assigner, g.workerId, g.lastSecond/g.bitsAllocator.GetUnitsPerSecond()))
+}
+
+// SetClock replaces the time source, e.g. with a generator.ManualClock in tests; nil restores generator.SystemClock
+func (g *core) SetClock(clock generator.Clock) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if clock == nil {
+		clock = generator.SystemClock
+	}
+	g.clock = clock
+}
+
+// fenceAbove makes the generator issue only after fence, the previous owner's last unix second
+func (g *core) fenceAbove(fence int64) {
+	if fence > 0 {
+		g.fenceAfter((fence+1)*g.bitsAllocator.GetUnitsPerSecond() - 1)
+	}
+}
+
+// fenceAfter makes the generator issue only after tick, in the allocator's time unit
+func (g *core) fenceAfter(tick int64) {
+	if tick >= g.lastSecond {
+		g.lastSecond, g.sequence = tick, g.bitsAllocator.GetMaxSequence()
+	}
+}
+
+// nextId generates the next UID
+func (g *core) nextId() (int64, error) {
+	if g.closed {
+		return 0, generator.ErrClosed
+	}
+	if err := g.watchdog.err(); err != nil {
+		return 0, err
+	}
+
+	// Handle clock rollback under the configured policy
+	unit := g.bitsAllocator.GetTimeUnit()
+	currentSecond, err := g.rollback.after(g.lastSecond, false, unit, g.getCurrentSecond, g.clock)
+	if err != nil {
+		return 0, err
+	}
+
+	// Increase sequence at the same second
+	sequence := g.sequence
+	if currentSecond == g.lastSecond {
+		g.sequence = (g.sequence + 1) & g.bitsAllocator.GetMaxSequence()
+		// Exceed sequence max, wait for the next second
+		if g.sequence == 0 {
+			if currentSecond, err = g.rollback.after(g.lastSecond, true, unit, g.getCurrentSecond, g.clock); err != nil {
+				g.sequence = g.bitsAllocator.GetMaxSequence()
+				return 0, err
+			}
+		}
+	} else {
+		// Reset sequence if it's a new second
+		g.sequence = 0
+	}
+
+	if err = g.highWater.reserve(currentSecond, unit); err != nil {
+		g.sequence = sequence
+		return 0, err
+	}
+	g.lastSecond = currentSecond
+
+	// Allocate the bits for UID
+	return g.bitsAllocator.Allocate(currentSecond-g.epochSeconds*g.bitsAllocator.GetUnitsPerSecond(), g.workerId, g.sequence), nil
+}
+
+// getCurrentSecond gets the current timestamp in the allocator's time unit
+func (g *core) getCurrentSecond() (int64, error) {
+	currentSecond := g.clock.Now().UnixNano() / int64(g.bitsAllocator.GetTimeUnit())
+	if currentSecond-g.epochSeconds*g.bitsAllocator.GetUnitsPerSecond() > g.bitsAllocator.GetMaxDeltaSeconds() {
+		return 0, fmt.Errorf("timestamp bits are exhausted. Refusing UID generation")
+	}
+	return currentSecond, nil
+}
+
+// Layout returns the generator's layout, to decode and compose UIDs without the generator
+func (c codec) Layout() generator.Layout {
+	return c.bitsAllocator.Layout(c.epochSeconds)
+}
+
+// Decode breaks a UID into its fields
+func (c codec) Decode(uid int64) generator.UIDInfo {
+	return c.bitsAllocator.Decode(c.epochSeconds, uid)
+}
+
+// ParseUID parses a UID and returns its fields as JSON
+func (c codec) ParseUID(uid int64) string {
+	return c.Decode(uid).JSON()
+}
@@ -1,169 +1,180 @@
 package generators
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/gomsr/atom-uid/config"
 	"github.com/gomsr/atom-uid/generator"
 	"github.com/gomsr/atom-uid/worker"
-	"sync"
 	"time"
 )
 
 // DefaultUidGenerator represents the UID generator
 type DefaultUidGenerator struct {
-	timeBits      int
-	workerBits    int
-	seqBits       int
-	epochStr      string
-	epochSeconds  int64
-	bitsAllocator *generator.BitsAllocator
-	workerId      int64
-	sequence      int64
-	lastSecond    int64
-	mu            sync.Mutex
+	timeBits   int
+	workerBits int
+	seqBits    int
+	epochStr   string
+	core
 }
 
 func NewWithConfig(conf *config.Config) (*DefaultUidGenerator, error) {
+	return NewWithConfigContext(context.Background(), conf)
+}
+
+// NewWithConfigContext is NewWithConfig with ctx bounding the worker ID assignment
+func NewWithConfigContext(ctx context.Context, conf *config.Config) (gtor *DefaultUidGenerator, err error) {
 	if conf == nil {
 		return nil, errors.New("config is nil")
 	}
+	if _, err = layoutWithConfig(conf); err != nil {
+		return nil, err
+	}
+
+	assigner, wid, err := assignWorkerIdWithConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			err = releaseAssigned(assigner, wid, err)
+		}
+	}()
+	if gtor, err = NewDefaultUidGenerator(conf.TimeBits, conf.WorkerBits, conf.SeqBits, wid, conf.EpochStr); err != nil {
+		return nil, err
+	}
+	if err = gtor.bitsAllocator.SplitWorkerIdBits(conf.DatacenterBits); err != nil {
+		return nil, err
+	}
+	if err = gtor.bitsAllocator.SetTimeUnit(time.Duration(conf.TimeUnit)); err != nil {
+		return nil, err
+	}
+	gtor.rollback = rollbackGuard{policy: conf.ClockRollback, tolerance: time.Duration(conf.MaxClockRollback)}
+	gtor.clock = clockWithConfig(conf)
+	if checkpoint, err := checkpointWithConfig(conf, assigner); err != nil {
+		return nil, err
+	} else if checkpoint != nil {
+		if gtor.highWater, err = openHighWater(ctx, checkpoint, wid, time.Duration(conf.CheckpointWindow), time.Duration(conf.CheckpointWait),
+			gtor.bitsAllocator.GetTimeUnit(), gtor.clock); err != nil {
+			return nil, err
+		}
+		gtor.fenceAfter(gtor.highWater.lastTick(gtor.bitsAllocator.GetTimeUnit()))
+	}
 
-	wid := conf.IdAssigner.Instance().NextWorkerId()
-	return NewDefaultUidGenerator(conf.TimeBits, conf.WorkerBits, conf.SeqBits, wid, conf.EpochStr)
+	gtor.assigner = assigner
+	gtor.fenceAbove(worker.Fence(assigner))
+	gtor.watchdog = startWatchdog(assigner, wid, time.Duration(conf.VerifyInterval))
+	return gtor, nil
 }
 
 func NewDefault(workerId ...int64) (*DefaultUidGenerator, error) {
-	var wid int64
 	if len(workerId) > 0 {
-		wid = workerId[0]
-	} else {
-		wid = worker.CloudflareWorkerId.Instance().NextWorkerId()
+		return NewDefaultUidGenerator(28, 11, 24, workerId[0])
 	}
 
-	return NewDefaultUidGenerator(28, 11, 24, wid)
-}
-
-// NewDefaultUidGenerator creates a new DefaultUidGenerator instance
-func NewDefaultUidGenerator(timeBits, workerBits, seqBits int, workerId int64, epochStr ...string) (*DefaultUidGenerator, error) {
-	//if timeBits+workerBits+seqBits+1 != generator.TotalBits {
-	//	return nil, errors.NewDefault("the sum of timeBits, workerBits, and seqBits must be 63")
-	//}
-
-	gtor := &DefaultUidGenerator{
-		timeBits:      timeBits,
-		workerBits:    workerBits,
-		seqBits:       seqBits,
-		bitsAllocator: generator.NewBitsAllocator(timeBits, workerBits, seqBits),
-		workerId:      workerId,
+	assigner, err := worker.CloudflareWorkerId.Instance()
+	if err != nil {
+		return nil, err
 	}
-
-	if len(epochStr) == 0 {
-		gtor.epochStr = generator.EpochStr
-		dt, _ := time.Parse(generator.EpochStrFormat, generator.EpochStr)
-		gtor.epochSeconds = dt.Unix()
-		return gtor, nil
+	maxWorkerId := generator.NewBitsAllocator(28, 11, 24).GetMaxWorkerId()
+	wid, err := assignWorkerId(context.Background(), assigner, maxWorkerId, worker.RejectOverflow)
+	if err != nil {
+		return nil, err
 	}
-
-	if parse, err := time.Parse(generator.EpochStrFormat, epochStr[0]); err != nil {
-		gtor.epochStr = generator.EpochStr
-		dt, _ := time.Parse(generator.EpochStrFormat, generator.EpochStr)
-		gtor.epochSeconds = dt.Unix()
-	} else {
-		gtor.epochStr = epochStr[0]
-		gtor.epochSeconds = parse.Unix()
+	gtor, err := NewDefaultUidGenerator(28, 11, 24, wid)
+	if err != nil {
+		return nil, releaseAssigned(assigner, wid, err)
 	}
-
 	return gtor, nil
 }
 
-// GetUID generates a unique ID
-func (g *DefaultUidGenerator) GetUID() (int64, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	return g.nextId()
+// assignWorkerIdWithConfig asks the configured assigner for a worker ID within the configured worker bits
+func assignWorkerIdWithConfig(ctx context.Context, conf *config.Config) (worker.IdAssigner, int64, error) {
+	assigner, err := conf.Assigner()
+	if err != nil {
+		return nil, 0, err
+	}
+	maxWorkerId := generator.NewBitsAllocator(conf.TimeBits, conf.WorkerBits, conf.SeqBits).GetMaxWorkerId()
+	wid, err := assignWorkerId(ctx, assigner, maxWorkerId, conf.WorkerIdOverflow)
+	if err != nil {
+		return nil, 0, err
+	}
+	return assigner, wid, nil
 }
 
-// MustUID generates a unique ID
-func (g *DefaultUidGenerator) MustUID() int64 {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	for i := 0; i < 10_000; i++ {
-		if id, err := g.nextId(); err == nil {
-			return id
-		}
+// layoutWithConfig validates the configured layout and epoch, so constructors fail before a worker ID is assigned
+func layoutWithConfig(conf *config.Config) (generator.Layout, error) {
+	_, epoch, err := parseEpoch(conf.EpochStr)
+	if err != nil {
+		return generator.Layout{}, err
 	}
+	layout := generator.Layout{TimestampBits: conf.TimeBits, WorkerIdBits: conf.WorkerBits, SequenceBits: conf.SeqBits,
+		DatacenterIdBits: conf.DatacenterBits, TimeUnit: time.Duration(conf.TimeUnit), Epoch: epoch, JSSafe: conf.JSSafe}
+	return layout, layout.ValidateAt(clockWithConfig(conf).Now())
+}
 
-	panic("UID generation failed")
+// releaseAssigned gives back the worker ID of a generator that failed to build. Nothing was issued,
+// so it is released with the fence it was assigned with; a release error is joined to err
+func releaseAssigned(assigner worker.IdAssigner, wid int64, err error) error {
+	return errors.Join(err, worker.Release(context.Background(), assigner, wid, worker.Fence(assigner)))
 }
 
-// nextId generates the next UID
-func (g *DefaultUidGenerator) nextId() (int64, error) {
-	currentSecond, err := g.getCurrentSecond()
-	if err != nil {
-		return 0, err
+// clockWithConfig returns a MonotonicClock with the default reconcile settings if configured, otherwise the system clock
+func clockWithConfig(conf *config.Config) generator.Clock {
+	if conf.MonotonicClock {
+		return generator.NewMonotonicClock(0, 0)
 	}
+	return generator.SystemClock
+}
 
-	// Handle clock rollback
-	if currentSecond < g.lastSecond {
-		refusedSeconds := g.lastSecond - currentSecond
-		return 0, fmt.Errorf("clock moved backwards. Refusing for %d seconds", refusedSeconds)
+// parseEpoch parses the first epochStr in generator.EpochStrFormat, generator.EpochStr if there is none or it is empty
+func parseEpoch(epochStr ...string) (string, time.Time, error) {
+	epoch := generator.EpochStr
+	if len(epochStr) > 0 && epochStr[0] != "" {
+		epoch = epochStr[0]
 	}
-
-	// Increase sequence at the same second
-	if currentSecond == g.lastSecond {
-		g.sequence = (g.sequence + 1) & g.bitsAllocator.GetMaxSequence()
-		// Exceed sequence max, wait for the next second
-		if g.sequence == 0 {
-			currentSecond = g.getNextSecond(g.lastSecond)
-		}
-	} else {
-		// Reset sequence if it's a new second
-		g.sequence = 0
+	dt, err := time.Parse(generator.EpochStrFormat, epoch)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("%w: epoch %q is not in the %s format", generator.ErrInvalidLayout, epoch, generator.EpochStrFormat)
 	}
-
-	g.lastSecond = currentSecond
-
-	// Allocate the bits for UID
-	return g.bitsAllocator.Allocate(currentSecond-g.epochSeconds, g.workerId, g.sequence), nil
+	return epoch, dt, nil
 }
 
-// getCurrentSecond gets the current second
-func (g *DefaultUidGenerator) getCurrentSecond() (int64, error) {
-	currentSecond := time.Now().Unix()
-	if currentSecond-g.epochSeconds > g.bitsAllocator.GetMaxDeltaSeconds() {
-		return 0, fmt.Errorf("timestamp bits are exhausted. Refusing UID generation")
+// assignWorkerId asks the assigner for a worker ID within maxWorkerId, so constructors can return its failure
+func assignWorkerId(ctx context.Context, assigner worker.IdAssigner, maxWorkerId int64, policy worker.OverflowPolicy) (int64, error) {
+	wid, err := worker.Assign(ctx, assigner, maxWorkerId, policy)
+	if err != nil {
+		return 0, fmt.Errorf("assign worker id: %w", err)
 	}
-	return currentSecond, nil
+	return wid, nil
 }
 
-// getNextSecond waits for the next second if the current second is exhausted
-func (g *DefaultUidGenerator) getNextSecond(lastTimestamp int64) int64 {
-	for {
-		timestamp := time.Now().Unix()
-		if timestamp > lastTimestamp {
-			return timestamp
-		}
+// NewDefaultUidGenerator creates a new DefaultUidGenerator instance, failing with an error wrapping
+// generator.ErrInvalidLayout for a layout or epoch that cannot issue UIDs
+func NewDefaultUidGenerator(timeBits, workerBits, seqBits int, workerId int64, epochStr ...string) (*DefaultUidGenerator, error) {
+	epoch, dt, err := parseEpoch(epochStr...)
+	if err != nil {
+		return nil, err
+	}
+	layout := generator.Layout{TimestampBits: timeBits, WorkerIdBits: workerBits, SequenceBits: seqBits, Epoch: dt}
+	allocator, err := layout.NewAllocator(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err = worker.RejectOverflow.Apply(workerId, allocator.GetMaxWorkerId()); err != nil {
+		return nil, err
 	}
-}
 
-// ParseUID parses a UID and returns its components as a string
-func (g *DefaultUidGenerator) ParseUID(uid int64) string {
-	totalBits := generator.TotalBits
-	signBits := g.bitsAllocator.GetSignBits()
-	timestampBits := g.bitsAllocator.GetTimestampBits()
-	workerIdBits := g.bitsAllocator.GetWorkerIdBits()
-	sequenceBits := g.bitsAllocator.GetSequenceBits()
-
-	// Parse UID
-	sequence := (uid << uint(totalBits-sequenceBits)) >> uint(totalBits-sequenceBits)
-	workerId := (uid << uint(timestampBits+signBits)) >> uint(totalBits-workerIdBits)
-	deltaSeconds := uid >> uint(workerIdBits+sequenceBits)
-
-	// Format time from epoch
-	thatTime := time.Unix(g.epochSeconds+deltaSeconds, 0)
-	return fmt.Sprintf("{\"UID\":\"%d\",\"timestamp\":\"%s\",\"workerId\":\"%d\",\"sequence\":\"%d\"}",
-		uid, thatTime.Format("2006-01-02 15:04:05"), workerId, sequence)
+	return &DefaultUidGenerator{
+		timeBits:   timeBits,
+		workerBits: workerBits,
+		seqBits:    seqBits,
+		epochStr:   epoch,
+		core: core{
+			codec:    codec{epochSeconds: dt.Unix(), bitsAllocator: allocator},
+			workerId: workerId,
+			clock:    generator.SystemClock,
+		},
+	}, nil
 }
@@ -1,15 +1,23 @@
 package generators
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/gomsr/atom-uid/config"
 	"github.com/gomsr/atom-uid/generator"
+	"github.com/gomsr/atom-uid/worker"
+	"github.com/gomsr/atom-uid/worker/workers"
+	"strings"
 	"testing"
+	"time"
 )
 
 var gtor generator.UidGenerator
 
-func init1() {
-	if g, err := NewDefault(); err != nil {
+func init() {
+	if g, err := NewDefault(1); err != nil {
 		panic(err)
 	} else {
 		gtor = g
@@ -34,3 +42,481 @@ func TestDefaultUidGenerator_ParseUID(t *testing.T) {
 	fmt.Println(gtor.ParseUID(1115435579803230208))
 
 }
+
+type failingAssigner struct{}
+
+func (failingAssigner) NextWorkerIdContext(context.Context) (int64, error) {
+	return 0, errors.New("backend unreachable")
+}
+
+func TestNewWithOptions_AssignerError(t *testing.T) {
+	if _, err := NewWithOptions(Assigner(failingAssigner{})); err == nil {
+		t.Fatal("expected assigner error")
+	}
+	if g, err := NewWithOptions(Assigner(failingAssigner{}), WorkerId(3)); err != nil || g.core.workerId != 3 {
+		t.Fatalf("NewWithOptions() = %v, %v, want explicit worker id without asking the assigner", g, err)
+	}
+}
+
+func TestWorkerIdOutOfRange(t *testing.T) {
+	if _, err := NewDefaultUidGenerator(64-1-3-3, 3, 3, 511, "2024-07-30"); err == nil {
+		t.Error("NewDefaultUidGenerator() accepted worker id 511 with 3 worker bits")
+	}
+	if _, err := NewWithOptions(WorkerBits(3), WorkerId(511)); err == nil {
+		t.Error("NewWithOptions() accepted worker id 511 with 3 worker bits")
+	}
+	if g, err := NewWithOptions(WorkerBits(3), WorkerId(511), Overflow(worker.WrapOverflow)); err != nil || g.core.workerId != 7 {
+		t.Errorf("NewWithOptions() = %v, %v, want worker id wrapped to 7", g, err)
+	}
+}
+
+func TestDefaultUidGenerator_Close(t *testing.T) {
+	store := workers.NewMemoryLeaseStore()
+	worker.Replace("close-test", func() worker.IdAssigner { return workers.NewLeaseAssigner(store, 0, time.Minute) })
+	conf := &config.Config{IdAssigner: "close-test", TimeBits: 28, WorkerBits: 11, SeqBits: 24}
+
+	g, err := NewWithConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.GetUID(); err != nil {
+		t.Fatal(err)
+	}
+	last := g.lastSecond
+	if err = g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = g.GetUID(); !errors.Is(err, generator.ErrClosed) {
+		t.Fatalf("GetUID() after Close error = %v, want ErrClosed", err)
+	}
+
+	next, err := NewWithConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer next.Close()
+	if next.workerId != g.workerId || next.lastSecond != last {
+		t.Fatalf("next owner of worker id %d starts at %d, want fenced at %d", next.workerId, next.lastSecond, last)
+	}
+}
+
+func TestDefaultUidGenerator_Watchdog(t *testing.T) {
+	store := workers.NewMemoryLeaseStore()
+	assigner := workers.NewLeaseAssigner(store, 0, time.Minute)
+	worker.Replace("watchdog-test", func() worker.IdAssigner { return assigner })
+	conf := &config.Config{IdAssigner: "watchdog-test", TimeBits: 28, WorkerBits: 11, SeqBits: 24, VerifyInterval: config.Duration(10 * time.Millisecond)}
+
+	g, err := NewWithConfigV2(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	if _, err = g.GetUID(); err != nil {
+		t.Fatal(err)
+	}
+
+	// another node takes the worker id over, e.g. after a partition outlived the lease
+	_ = store.Release(context.Background(), g.core.workerId, assigner.Owner, 0)
+	_, _ = store.Acquire(context.Background(), "other", g.core.workerId, time.Minute)
+
+	var lost *generator.OwnershipLostError
+	for deadline := time.Now().Add(time.Second); !errors.As(err, &lost); {
+		if time.Now().After(deadline) {
+			t.Fatal("ownership loss was not detected")
+		}
+		time.Sleep(5 * time.Millisecond)
+		_, err = g.GetUID()
+	}
+	if lost.WorkerId != g.core.workerId || !errors.Is(err, workers.ErrLeaseLost) {
+		t.Fatalf("GetUID() error = %v", err)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Fatal("MustUID() kept issuing after ownership was lost")
+		}
+	}()
+	g.MustUID()
+}
+
+func TestDatacenterMachineId(t *testing.T) {
+	worker.Replace("split-test", func() worker.IdAssigner { return worker.Fixed(7) })
+	conf := &config.Config{IdAssigner: "split-test", TimeBits: 28, WorkerBits: 11, SeqBits: 24, DatacenterBits: 5, DatacenterId: 3}
+
+	fromConf, err := NewWithConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromOptions, err := NewWithOptions(DatacenterBits(5), DatacenterMachineId(3, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range []generator.UidGenerator{fromConf, fromOptions} {
+		parsed := g.ParseUID(g.MustUID())
+		if !strings.Contains(parsed, `"workerId":199,"datacenterId":3,"machineId":7`) {
+			t.Errorf("ParseUID() = %s, want datacenter 3 and machine 7", parsed)
+		}
+	}
+	if info := fromOptions.Decode(fromOptions.MustUID()); info.String() != fmt.Sprintf(
+		"uid %d: time %s, worker 199 (datacenter 3, machine 7), sequence %d, layout 1+28+(5+6)+24",
+		info.UID, info.Time.Format(time.RFC3339Nano), info.Sequence) {
+		t.Errorf("String() = %s", info)
+	}
+
+	if _, err = NewWithOptions(DatacenterBits(5), DatacenterMachineId(32, 7)); err == nil {
+		t.Error("expected error for datacenter id outside 5 bits")
+	}
+	conf.DatacenterBits = 11
+	if _, err = NewWithConfig(conf); err == nil {
+		t.Error("expected error for datacenter bits leaving no machine bits")
+	}
+}
+
+func TestTimeUnit(t *testing.T) {
+	g, err := NewWithOptions(TimeBits(41), WorkerBits(10), SeqBits(12), WorkerId(1), TimeUnit(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lifetime := g.BitsAllocator.GetLifetime(); lifetime < 69*365*24*time.Hour || lifetime > 70*365*24*time.Hour {
+		t.Errorf("GetLifetime() = %s, want about 69.7 years", lifetime)
+	}
+
+	before := time.Now().Truncate(time.Millisecond)
+	var last int64
+	for i := 0; i < 10_000; i++ {
+		uid := g.MustUID()
+		if uid <= last {
+			t.Fatalf("uid %d after %d is not increasing", uid, last)
+		}
+		last = uid
+	}
+	after := time.Now()
+
+	var parsed generator.UIDInfo
+	if err = json.Unmarshal([]byte(g.ParseUID(last)), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if parsed.UID != last || parsed.WorkerId != 1 || parsed.Layout != "1+41+10+12/1ms" {
+		t.Errorf("ParseUID() = %+v, want uid %d of worker 1 in 1+41+10+12/1ms", parsed, last)
+	}
+	if at := parsed.Time; at.Before(before) || at.After(after) || at.Location() != time.UTC {
+		t.Errorf("ParseUID() time = %s, want between %s and %s in UTC", at, before, after)
+	}
+
+	if _, err = NewWithOptions(TimeBits(41), WorkerId(1), SeqBits(11), TimeUnit(4*time.Millisecond)); err != nil {
+		t.Error("4ms divides a second and should be accepted")
+	}
+	if _, err = NewWithOptions(WorkerId(1), TimeUnit(4*time.Millisecond)); !errors.Is(err, generator.ErrInvalidLayout) {
+		t.Errorf("error = %v, want the 28 timestamp bits in 4ms units run out", err)
+	}
+	if _, err = NewWithOptions(WorkerId(1), TimeUnit(7*time.Millisecond)); err == nil {
+		t.Error("expected error for a time unit that does not divide a second")
+	}
+}
+
+func TestClockRollback(t *testing.T) {
+	clock := generator.NewManualClock(time.Now())
+	layout := func(ops ...OptionFunc) *DefaultUidGeneratorV2 {
+		g, err := NewWithOptions(append([]OptionFunc{TimeBits(41), WorkerBits(10), SeqBits(12), WorkerId(1), TimeUnit(time.Millisecond)}, ops...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+	reject := layout(Clock(clock))
+	wait := layout(Clock(clock), ClockRollback(generator.WaitRollback, 10*time.Millisecond))
+	for _, g := range []*DefaultUidGeneratorV2{reject, wait} {
+		g.MustUID()
+	}
+
+	clock.Add(-5 * time.Millisecond)
+	var rollback *generator.ClockRollbackError
+	if _, err := reject.GetUID(); !errors.As(err, &rollback) || rollback.Drift != 5*time.Millisecond {
+		t.Fatalf("GetUID() error = %v, want a 5ms ClockRollbackError", err)
+	}
+	if _, err := wait.GetUID(); err != nil {
+		t.Fatalf("GetUID() error = %v, want the 5ms drift waited out", err)
+	}
+
+	monotonic := layout(Clock(generator.NewMonotonicClock(0, 0)))
+	for i := 0; i < 1_000; i++ {
+		if _, err := monotonic.GetUID(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	clock := generator.NewManualClock(time.UnixMilli(1_800_000_000_000))
+	store := workers.NewFileCheckpoint(t.TempDir())
+	layout := func(wait time.Duration) (*DefaultUidGeneratorV2, error) {
+		return NewWithOptions(TimeBits(41), WorkerBits(10), SeqBits(12), WorkerId(1), TimeUnit(time.Millisecond),
+			Clock(clock), Checkpoint(store, time.Second, wait))
+	}
+
+	g, err := layout(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := g.MustUID()
+	if mark, _ := store.LoadHighWater(context.Background(), 1); mark != 1_800_000_001_001 {
+		t.Fatalf("mark = %d, want the issued tick reserved a second ahead", mark)
+	}
+
+	// Restart after the clock was stepped back behind the mark
+	clock.Add(-time.Minute)
+	g, err = layout(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rollback *generator.ClockRollbackError
+	if _, err = g.GetUID(); !errors.As(err, &rollback) {
+		t.Fatalf("GetUID() error = %v, want a ClockRollbackError below the mark", err)
+	}
+	if _, err = layout(time.Second); !errors.As(err, &rollback) {
+		t.Fatalf("layout(1s) error = %v, want a ClockRollbackError beyond the wait", err)
+	}
+
+	g, err = layout(2 * time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid := g.MustUID(); uid <= last {
+		t.Errorf("uid %d after restart is not above %d", uid, last)
+	}
+	if !clock.Now().After(time.UnixMilli(1_800_000_001_000)) {
+		t.Errorf("clock %v did not wait past the mark", clock.Now())
+	}
+}
+
+func TestCheckpoint_Restart(t *testing.T) {
+	clock := generator.NewManualClock(time.UnixMilli(1_800_000_000_000))
+	store := workers.NewFileCheckpoint(t.TempDir())
+	open := func() *DefaultUidGeneratorV2 {
+		g, err := NewWithOptions(TimeBits(41), WorkerBits(10), SeqBits(12), WorkerId(1), TimeUnit(time.Millisecond),
+			Clock(clock), Checkpoint(store, 0, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return g
+	}
+
+	// A clean stop settles the mark, so the next start issues at once
+	g := open()
+	last := g.MustUID()
+	if err := g.Close(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Add(time.Millisecond)
+	start := clock.Now()
+	if uid, err := open().GetUID(); err != nil || uid <= last {
+		t.Fatalf("GetUID() after a clean restart = %d, %v, want above %d", uid, err, last)
+	}
+	if !clock.Now().Equal(start) {
+		t.Errorf("clean restart waited %s", clock.Now().Sub(start))
+	}
+
+	// A stop without Close leaves the reservation of the default window, which is waited out
+	g = open()
+	last = g.MustUID()
+	if uid, err := open().GetUID(); err != nil || uid <= last {
+		t.Fatalf("GetUID() after a crash = %d, %v, want above %d", uid, err, last)
+	}
+	if waited := clock.Now().Sub(start); waited < DefaultCheckpointWindow {
+		t.Errorf("restart after a crash waited %s, want the %s reservation", waited, DefaultCheckpointWindow)
+	}
+}
+
+func TestManualClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := generator.NewManualClock(start)
+
+	// Exhausting a second's sequence moves on to the next second without sleeping
+	g, err := NewDefaultUidGenerator(28, 22, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetClock(clock)
+	for i := 0; i < 5; i++ {
+		g.MustUID()
+	}
+	if got := clock.Now().Sub(start); got != time.Second {
+		t.Errorf("clock advanced %s after overflowing the sequence, want 1s", got)
+	}
+
+	// The timestamp bits of a 2-bit layout run out 4 seconds after the epoch
+	clock.Set(start.Add(3 * time.Second))
+	short, err := NewWithOptions(TimeBits(2), WorkerBits(50), SeqBits(11), WorkerId(1), EpochStr("2024-01-01"), Clock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = short.GetUID(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Set(start.Add(4 * time.Second))
+	if _, err = short.GetUID(); err == nil || !strings.Contains(err.Error(), "exhausted") {
+		t.Errorf("GetUID() error = %v, want the timestamp bits exhausted", err)
+	}
+
+	cached, err := NewCachedUidGeneratorClock(clock, 28, 22, 13, 0, PaddingFactor, 0, 1, "2024-01-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cached.Close()
+	if uid := cached.MustUID(); uid>>35 != 5 {
+		t.Errorf("cached uid %d is at second %d of the epoch, want 5", uid, uid>>35)
+	}
+}
+
+func TestNextRange(t *testing.T) {
+	clock := generator.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := NewWithOptions(TimeBits(28), WorkerBits(33), SeqBits(2), WorkerId(1), Clock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ranges, err := g.NextRange(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lens []int64
+	for _, r := range ranges {
+		lens = append(lens, r.Len())
+	}
+	if fmt.Sprint(lens) != "[4 4 2]" {
+		t.Errorf("range lengths = %v, want [4 4 2] across three seconds", lens)
+	}
+
+	uids, err := g.GetUIDs(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(uids) != 3 || uids[0] != ranges[2].Last+1 || uids[2] <= uids[1] {
+		t.Errorf("GetUIDs(3) = %v, want to continue after %d", uids, ranges[2].Last)
+	}
+	if _, err = g.GetUIDs(0); err == nil {
+		t.Error("GetUIDs(0) succeeded")
+	}
+
+	cached, err := NewCachedUidGeneratorClock(clock, 28, 22, 13, 0, PaddingFactor, 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cached.Close()
+	cachedRanges, err := cached.NextRange(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cachedRanges) != 1 || cachedRanges[0].Len() != 100 {
+		t.Errorf("cached NextRange(100) = %v, want one contiguous run", cachedRanges)
+	}
+	if _, err = cached.GetUIDs(1 << 13); err == nil {
+		t.Error("cached GetUIDs() of the whole ring buffer succeeded")
+	}
+}
+
+func TestInvalidLayout(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []OptionFunc
+	}{
+		{"over budget", []OptionFunc{TimeBits(41), WorkerBits(11), SeqBits(12)}},
+		{"zero sequence bits", []OptionFunc{SeqBits(0)}},
+		{"negative worker bits", []OptionFunc{WorkerBits(-1)}},
+		{"shift into the sign bit", []OptionFunc{TimeBits(28), WorkerBits(11), SeqBits(63)}},
+		{"bad epoch", []OptionFunc{EpochStr("2024/01/01")}},
+		{"future epoch", []OptionFunc{EpochStr("2999-01-01")}},
+		{"exhausted epoch", []OptionFunc{EpochStr("2000-01-01")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWithOptions(append(tt.ops, WorkerId(1))...); !errors.Is(err, generator.ErrInvalidLayout) {
+				t.Errorf("NewWithOptions() error = %v, want generator.ErrInvalidLayout", err)
+			}
+		})
+	}
+
+	if _, err := NewDefaultUidGenerator(28, 22, 13, 1, "2024-13-01"); !errors.Is(err, generator.ErrInvalidLayout) {
+		t.Errorf("NewDefaultUidGenerator() error = %v, want the bad epoch rejected", err)
+	}
+	if _, err := NewCachedUidGenerator(40, 22, 13, BoostPower, PaddingFactor, 0, 1); !errors.Is(err, generator.ErrInvalidLayout) {
+		t.Errorf("NewCachedUidGenerator() error = %v, want the over-budget layout rejected", err)
+	}
+}
+
+func TestJSSafe(t *testing.T) {
+	clock := generator.NewManualClock(time.Date(2090, 1, 1, 0, 0, 0, 0, time.UTC))
+	g, err := NewWithOptions(JSSafe(), WorkerId(255), EpochStr("2024-01-01"), Clock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uids, err := g.GetUIDs(1 << 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := uids[len(uids)-1]
+	if last > generator.MaxSafeInteger {
+		t.Errorf("uid %d exceeds Number.MAX_SAFE_INTEGER", last)
+	}
+	info := g.Decode(last)
+	if info.WorkerId != 255 || info.Sequence != 1<<14-1 || !info.Time.Equal(clock.Now().Truncate(time.Second)) {
+		t.Errorf("Decode() = %s, want worker 255, the last sequence at %s", info, clock.Now())
+	}
+	if !g.Layout().JSSafe {
+		t.Error("Layout() of the JSSafe preset is not JSSafe")
+	}
+
+	if _, err = NewWithOptions(JSSafe(), SeqBits(15), WorkerId(1)); !errors.Is(err, generator.ErrInvalidLayout) {
+		t.Errorf("error = %v, want 54 bits rejected", err)
+	}
+	conf := &config.Config{IdAssigner: worker.EnvWorkerId, TimeBits: 28, WorkerBits: 22, SeqBits: 13, JSSafe: true}
+	t.Setenv("WORKER_ID", "1")
+	if _, err = NewWithConfig(conf); !errors.Is(err, generator.ErrInvalidLayout) {
+		t.Errorf("NewWithConfig() error = %v, want the 63-bit layout rejected as unsafe", err)
+	}
+	conf = &config.Config{IdAssigner: worker.EnvWorkerId, TimeBits: 30, WorkerBits: 8, SeqBits: 15, JSSafe: true}
+	v2, err := NewWithConfigV2(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := v2.Layout(); !l.JSSafe || l.TimestampBits != 30 || l.SequenceBits != 15 {
+		t.Errorf("Layout() = %+v, want the configured JS-safe bits", l)
+	}
+}
+
+func TestFailedConstructorReleasesWorkerId(t *testing.T) {
+	store := workers.NewMemoryLeaseStore()
+	var asked int
+	worker.Replace("release-test", func() worker.IdAssigner {
+		asked++
+		return workers.NewLeaseAssigner(store, 0, time.Minute)
+	})
+
+	for _, kind := range []generator.Type{generator.DefaultUid, generator.DefaultUidV2} {
+		// A lease store cannot keep a high-water mark, which only fails after the worker ID is assigned
+		conf := &config.Config{IdAssigner: "release-test", Generator: kind, TimeBits: 28, WorkerBits: 1, SeqBits: 24, Checkpoint: true}
+		for i := 0; i < 3; i++ {
+			if _, err := New(conf); err == nil || !strings.Contains(err.Error(), "checkpoint") {
+				t.Fatalf("%s: New() error = %v, want the checkpoint rejected", kind, err)
+			}
+		}
+
+		// An invalid layout fails before any worker ID is asked for
+		asked = 0
+		if _, err := New(&config.Config{IdAssigner: "release-test", Generator: kind, TimeBits: 28, WorkerBits: 22, SeqBits: 13, JSSafe: true}); !errors.Is(err, generator.ErrInvalidLayout) || asked != 0 {
+			t.Fatalf("%s: New() error = %v after %d assigners, want ErrInvalidLayout before any", kind, err, asked)
+		}
+	}
+
+	// The cached generator has no checkpoint and says so before asking for a worker ID
+	asked = 0
+	if _, err := New(&config.Config{IdAssigner: "release-test", Generator: generator.CachedUid, TimeBits: 28, WorkerBits: 1, SeqBits: 24, Checkpoint: true}); err == nil || asked != 0 {
+		t.Fatalf("cached: New() error = %v after %d assigners, want the checkpoint rejected before any", err, asked)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := store.Acquire(context.Background(), "other", 1, time.Minute); err != nil {
+			t.Fatalf("worker id %d was not released: %v", i, err)
+		}
+	}
+}
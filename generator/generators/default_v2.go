@@ -1,21 +1,36 @@
 package generators
 
 import (
+	"context"
 	"errors"
-	"fmt"
 	"github.com/gomsr/atom-uid/config"
 	"github.com/gomsr/atom-uid/generator"
 	"github.com/gomsr/atom-uid/worker"
-	"sync"
 	"time"
 )
 
 type DefaultConfig struct {
-	timeBits   int
-	workerBits int
-	seqBits    int
-	workerId   int64
-	epochStr   string
+	timeBits    int
+	workerBits  int
+	seqBits     int
+	workerId    int64
+	hasWorkerId bool
+	assigner    worker.IdAssigner
+	overflow    worker.OverflowPolicy
+	verify      time.Duration
+	timeUnit    time.Duration
+	jsSafe      bool
+	rollback    rollbackGuard
+	clock       generator.Clock
+	checkpoint  worker.Checkpoint
+	cpWindow    time.Duration
+	cpWait      time.Duration
+	epochStr    string
+
+	datacenterBits int
+	datacenterId   int64
+	machineId      int64
+	hasSplitId     bool
 }
 type OptionFunc func(v *DefaultConfig)
 
@@ -37,6 +52,83 @@ func SeqBits(seqBits int) OptionFunc {
 func WorkerId(workerId int64) OptionFunc {
 	return func(config *DefaultConfig) {
 		config.workerId = workerId
+		config.hasWorkerId = true
+	}
+}
+
+// TimeUnit sets what one step of the timestamp is worth, e.g. time.Millisecond, default time.Second
+func TimeUnit(unit time.Duration) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.timeUnit = unit
+	}
+}
+
+// ClockRollback sets how a backwards clock step up to tolerance is handled, default generator.RejectRollback
+func ClockRollback(policy generator.RollbackPolicy, tolerance time.Duration) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.rollback = rollbackGuard{policy: policy, tolerance: tolerance}
+	}
+}
+
+// Clock sets the time source, default generator.SystemClock
+func Clock(clock generator.Clock) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.clock = clock
+	}
+}
+
+// Checkpoint keeps a high-water mark of issued timestamps in store, reserving window ahead per save,
+// and waits out that window plus up to wait at startup for the clock to pass the mark; beyond the
+// window with wait 0 it refuses until then
+func Checkpoint(store worker.Checkpoint, window, wait time.Duration) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.checkpoint, config.cpWindow, config.cpWait = store, window, wait
+	}
+}
+
+// JSSafe switches to the layout of generator.NewJSSafeLayout, 1+31+8+14, and rejects any bits set
+// afterwards that would take UIDs past generator.MaxSafeInteger
+func JSSafe() OptionFunc {
+	return func(config *DefaultConfig) {
+		preset, _ := generator.NewJSSafeLayout("") // the default epoch keeps the preset valid until 2084
+		config.timeBits, config.workerBits, config.seqBits = preset.TimestampBits, preset.WorkerIdBits, preset.SequenceBits
+		config.jsSafe = true
+	}
+}
+
+// DatacenterBits splits the high bits of the worker ID off as a datacenter ID
+func DatacenterBits(datacenterBits int) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.datacenterBits = datacenterBits
+	}
+}
+
+// DatacenterMachineId sets the worker ID from static datacenter and machine IDs, used with DatacenterBits
+func DatacenterMachineId(datacenterId, machineId int64) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.datacenterId, config.machineId = datacenterId, machineId
+		config.hasSplitId = true
+	}
+}
+
+// Assigner sets the IdAssigner asked for a worker ID when no WorkerId option is given, default cloudflare
+func Assigner(assigner worker.IdAssigner) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.assigner = assigner
+	}
+}
+
+// Overflow sets how a worker ID outside the layout's worker bits is handled, default worker.RejectOverflow
+func Overflow(policy worker.OverflowPolicy) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.overflow = policy
+	}
+}
+
+// VerifyInterval re-verifies worker ID ownership through the assigner this often, 0 disables
+func VerifyInterval(interval time.Duration) OptionFunc {
+	return func(config *DefaultConfig) {
+		config.verify = interval
 	}
 }
 func EpochStr(epochStr string) OptionFunc {
@@ -48,162 +140,132 @@ func EpochStr(epochStr string) OptionFunc {
 // DefaultUidGeneratorV2 represents the UID generator
 type DefaultUidGeneratorV2 struct {
 	*DefaultConfig
-	epochSeconds  int64
 	BitsAllocator *generator.BitsAllocator
-	sequence      int64
-	lastSecond    int64
-	mu            sync.Mutex
+	core
 }
 
 func NewWithConfigV2(conf *config.Config) (*DefaultUidGeneratorV2, error) {
+	return NewWithConfigV2Context(context.Background(), conf)
+}
+
+// NewWithConfigV2Context is NewWithConfigV2 with ctx bounding the worker ID assignment
+func NewWithConfigV2Context(ctx context.Context, conf *config.Config) (*DefaultUidGeneratorV2, error) {
 	if conf == nil {
 		return nil, errors.New("config is nil")
 	}
+	if _, err := layoutWithConfig(conf); err != nil {
+		return nil, err
+	}
 
-	wid := conf.IdAssigner.Instance().NextWorkerId()
-	return NewWithOptions(TimeBits(conf.TimeBits), WorkerBits(conf.WorkerBits),
-		SeqBits(conf.SeqBits), WorkerId(wid), EpochStr(conf.EpochStr))
+	assigner, wid, err := assignWorkerIdWithConfig(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint, err := checkpointWithConfig(conf, assigner)
+	if err != nil {
+		return nil, releaseAssigned(assigner, wid, err)
+	}
+	var ops []OptionFunc
+	if conf.JSSafe {
+		ops = append(ops, JSSafe()) // the configured bits below replace the preset's and must stay within 53
+	}
+	ops = append(ops, Checkpoint(checkpoint, time.Duration(conf.CheckpointWindow), time.Duration(conf.CheckpointWait)), TimeBits(conf.TimeBits), WorkerBits(conf.WorkerBits), SeqBits(conf.SeqBits),
+		WorkerId(wid), Assigner(assigner), Overflow(conf.WorkerIdOverflow), DatacenterBits(conf.DatacenterBits),
+		TimeUnit(time.Duration(conf.TimeUnit)), ClockRollback(conf.ClockRollback, time.Duration(conf.MaxClockRollback)), Clock(clockWithConfig(conf)), VerifyInterval(time.Duration(conf.VerifyInterval)), EpochStr(conf.EpochStr))
+	gtor, err := NewWithOptions(ops...)
+	if err != nil {
+		return nil, releaseAssigned(assigner, wid, err)
+	}
+	return gtor, nil
 }
 
 func NewV2(workerId ...int64) (*DefaultUidGeneratorV2, error) {
-	var wid int64
 	if len(workerId) > 0 {
-		wid = workerId[0]
-	} else {
-		wid = worker.CloudflareWorkerId.Instance().NextWorkerId()
+		return NewWithOptions(TimeBits(28), WorkerBits(11), SeqBits(24), WorkerId(workerId[0]))
 	}
 
-	return NewWithOptions(TimeBits(28), WorkerBits(11), SeqBits(24), WorkerId(wid))
+	return NewWithOptions(TimeBits(28), WorkerBits(11), SeqBits(24))
 }
 
-// NewWithOptions creates a new DefaultUidGenerator instance
+// NewWithOptions creates a new DefaultUidGenerator instance, failing with an error wrapping
+// generator.ErrInvalidLayout for a layout or epoch that cannot issue UIDs
 func NewWithOptions(ops ...OptionFunc) (*DefaultUidGeneratorV2, error) {
-	//if timeBits+workerBits+seqBits+1 != generator.TotalBits {
-	//	return nil, errors.NewDefault("the sum of timeBits, workerBits, and seqBits must be 63")
-	//}
-
 	dc := &DefaultConfig{
 		timeBits:   28,
 		workerBits: 11,
 		seqBits:    24,
-		workerId:   worker.CloudflareWorkerId.Instance().NextWorkerId(),
+		clock:      generator.SystemClock,
 	}
 	for _, opFunc := range ops {
 		opFunc(dc)
 	}
-
-	allocator := generator.NewBitsAllocator(dc.timeBits, dc.workerBits, dc.seqBits)
-	gtor := &DefaultUidGeneratorV2{
-		DefaultConfig: dc,
-		BitsAllocator: allocator,
-	}
-
-	if len(dc.epochStr) == 0 {
-		gtor.epochStr = generator.EpochStr
-		dt, _ := time.Parse(generator.EpochStrFormat, generator.EpochStr)
-		gtor.epochSeconds = dt.Unix()
-		return gtor, nil
-	}
-
-	if parse, err := time.Parse(generator.EpochStrFormat, dc.epochStr); err != nil {
-		gtor.epochStr = generator.EpochStr
-		dt, _ := time.Parse(generator.EpochStrFormat, generator.EpochStr)
-		gtor.epochSeconds = dt.Unix()
-	} else {
-		gtor.epochStr = dc.epochStr
-		gtor.epochSeconds = parse.Unix()
+	if dc.clock == nil {
+		dc.clock = generator.SystemClock
 	}
 
-	return gtor, nil
-}
-
-// GetUID generates a unique ID
-func (g *DefaultUidGeneratorV2) GetUID() (int64, error) {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	return g.nextId()
-}
-
-// MustUID generates a unique ID
-func (g *DefaultUidGeneratorV2) MustUID() int64 {
-	g.mu.Lock()
-	defer g.mu.Unlock()
-
-	for i := 0; i < 10_000; i++ {
-		if id, err := g.nextId(); err == nil {
-			return id
-		}
-	}
-
-	panic("UID generation failed")
-}
-
-// nextId generates the next UID
-func (g *DefaultUidGeneratorV2) nextId() (int64, error) {
-	currentSecond, err := g.getCurrentSecond()
+	epoch, dt, err := parseEpoch(dc.epochStr)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-
-	// Handle clock rollback
-	if currentSecond < g.lastSecond {
-		refusedSeconds := g.lastSecond - currentSecond
-		return 0, fmt.Errorf("clock moved backwards. Refusing for %d seconds", refusedSeconds)
+	dc.epochStr = epoch
+	layout := generator.Layout{TimestampBits: dc.timeBits, WorkerIdBits: dc.workerBits, SequenceBits: dc.seqBits,
+		DatacenterIdBits: dc.datacenterBits, TimeUnit: dc.timeUnit, Epoch: dt, JSSafe: dc.jsSafe}
+	allocator, err := layout.NewAllocator(dc.clock.Now())
+	if err != nil {
+		return nil, err
 	}
-
-	// Increase sequence at the same second
-	if currentSecond == g.lastSecond {
-		g.sequence = (g.sequence + 1) & g.BitsAllocator.GetMaxSequence()
-		// Exceed sequence max, wait for the next second
-		if g.sequence == 0 {
-			currentSecond = g.getNextSecond(g.lastSecond)
+	assigned := false // by this constructor, which must give the worker ID back if it fails later
+	if dc.hasSplitId {
+		wid, err := allocator.ComposeWorkerId(dc.datacenterId, dc.machineId)
+		if err != nil {
+			return nil, err
 		}
+		dc.workerId, dc.hasWorkerId = wid, true
+	} else if dc.hasWorkerId {
+		wid, err := dc.overflow.Apply(dc.workerId, allocator.GetMaxWorkerId())
+		if err != nil {
+			return nil, err
+		}
+		dc.workerId = wid
 	} else {
-		// Reset sequence if it's a new second
-		g.sequence = 0
+		if dc.assigner == nil {
+			assigner, err := worker.CloudflareWorkerId.Instance()
+			if err != nil {
+				return nil, err
+			}
+			dc.assigner = assigner
+		}
+		wid, err := assignWorkerId(context.Background(), dc.assigner, allocator.GetMaxWorkerId(), dc.overflow)
+		if err != nil {
+			return nil, err
+		}
+		dc.workerId, assigned = wid, true
 	}
-
-	g.lastSecond = currentSecond
-
-	// Allocate the bits for UID
-	return g.BitsAllocator.Allocate(currentSecond-g.epochSeconds, g.workerId, g.sequence), nil
-}
-
-// getCurrentSecond gets the current second
-func (g *DefaultUidGeneratorV2) getCurrentSecond() (int64, error) {
-	currentSecond := time.Now().Unix()
-	if currentSecond-g.epochSeconds > g.BitsAllocator.GetMaxDeltaSeconds() {
-		return 0, fmt.Errorf("timestamp bits are exhausted. Refusing UID generation")
+	gtor := &DefaultUidGeneratorV2{
+		DefaultConfig: dc,
+		BitsAllocator: allocator,
+		core: core{
+			codec:    codec{epochSeconds: dt.Unix(), bitsAllocator: allocator},
+			workerId: dc.workerId,
+			assigner: dc.assigner,
+			rollback: dc.rollback,
+			clock:    dc.clock,
+		},
 	}
-	return currentSecond, nil
-}
 
-// getNextSecond waits for the next second if the current second is exhausted
-func (g *DefaultUidGeneratorV2) getNextSecond(lastTimestamp int64) int64 {
-	for {
-		timestamp := time.Now().Unix()
-		if timestamp > lastTimestamp {
-			return timestamp
+	if dc.checkpoint != nil {
+		if gtor.highWater, err = openHighWater(context.Background(), dc.checkpoint, dc.workerId, dc.cpWindow, dc.cpWait,
+			allocator.GetTimeUnit(), dc.clock); err != nil {
+			if assigned {
+				err = releaseAssigned(dc.assigner, dc.workerId, err)
+			}
+			return nil, err
 		}
+		gtor.fenceAfter(gtor.highWater.lastTick(allocator.GetTimeUnit()))
 	}
-}
-
-// ParseUID parses a UID and returns its components as a string
-func (g *DefaultUidGeneratorV2) ParseUID(uid int64) string {
-	totalBits := generator.TotalBits
-	signBits := g.BitsAllocator.GetSignBits()
-	timestampBits := g.BitsAllocator.GetTimestampBits()
-	workerIdBits := g.BitsAllocator.GetWorkerIdBits()
-	sequenceBits := g.BitsAllocator.GetSequenceBits()
-
-	// Parse UID
-	sequence := (uid << uint(totalBits-sequenceBits)) >> uint(totalBits-sequenceBits)
-	workerId := (uid << uint(timestampBits+signBits)) >> uint(totalBits-workerIdBits)
-	deltaSeconds := uid >> uint(workerIdBits+sequenceBits)
-
-	// Format time from epoch
-	thatTime := time.Unix(g.epochSeconds+deltaSeconds, 0)
-	return fmt.Sprintf("{\"UID\":\"%d\",\"timestamp\":\"%s\",\"workerId\":\"%d\",\"sequence\":\"%d\"}",
-		uid, thatTime.Format("2006-01-02 15:04:05"), workerId, sequence)
+	if dc.assigner != nil {
+		gtor.fenceAbove(worker.Fence(dc.assigner))
+		gtor.watchdog = startWatchdog(dc.assigner, dc.workerId, dc.verify)
+	}
+	return gtor, nil
 }
@@ -0,0 +1,84 @@
+package generators
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomsr/atom-uid/config"
+	"github.com/gomsr/atom-uid/generator"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory builds a generator from the config.
+type Factory func(ctx context.Context, conf *config.Config) (generator.UidGenerator, error)
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[generator.Type]Factory{}
+)
+
+func init() {
+	Register(generator.DefaultUid, func(ctx context.Context, conf *config.Config) (generator.UidGenerator, error) {
+		return NewWithConfigContext(ctx, conf)
+	})
+	Register(generator.DefaultUidV2, func(ctx context.Context, conf *config.Config) (generator.UidGenerator, error) {
+		return NewWithConfigV2Context(ctx, conf)
+	})
+	Register(generator.CachedUid, func(ctx context.Context, conf *config.Config) (generator.UidGenerator, error) {
+		return NewCachedWithConfigContext(ctx, conf)
+	})
+}
+
+// Register makes a generator kind available by name. It panics if the name is already registered.
+func Register(t generator.Type, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	t = t.Normalize()
+	if factory == nil {
+		panic("generators: Register with nil factory")
+	}
+	if _, dup := registry[t]; dup {
+		panic("generators: Register called twice for type " + string(t))
+	}
+	registry[t] = factory
+}
+
+// Types returns the registered generator names, sorted.
+func Types() []generator.Type {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]generator.Type, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// New builds the generator registered as conf.Generator.
+func New(conf *config.Config) (generator.UidGenerator, error) {
+	return NewContext(context.Background(), conf)
+}
+
+// NewContext is New with ctx bounding the worker ID assignment
+func NewContext(ctx context.Context, conf *config.Config) (generator.UidGenerator, error) {
+	if conf == nil {
+		return nil, errors.New("config is nil")
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[conf.Generator.Normalize()]
+	registryMu.RUnlock()
+	if !ok {
+		names := make([]string, 0)
+		for _, t := range Types() {
+			names = append(names, string(t))
+		}
+		return nil, fmt.Errorf("unknown generator %q, registered: %s", string(conf.Generator), strings.Join(names, ", "))
+	}
+	return factory(ctx, conf)
+}
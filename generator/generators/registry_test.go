@@ -0,0 +1,35 @@
+package generators
+
+import (
+	"encoding/json"
+	"github.com/gomsr/atom-uid/config"
+	"github.com/gomsr/atom-uid/worker"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	worker.Replace("generators-test", func() worker.IdAssigner { return worker.Legacy(fixedWorkerId(5)) })
+
+	var conf config.Config
+	data := `{"id_assigner": "generators-test", "generator": "default_v2", "time_bits": 28, "worker_bits": 11, "seq_bits": 24}`
+	if err := json.Unmarshal([]byte(data), &conf); err != nil {
+		t.Fatal(err)
+	}
+	g, err := New(&conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v2, ok := g.(*DefaultUidGeneratorV2); !ok || v2.core.workerId != 5 {
+		t.Fatalf("New() = %T %+v, want DefaultUidGeneratorV2 with worker id 5", g, g)
+	}
+
+	conf.Generator = "snowflake"
+	if _, err = New(&conf); err == nil || !strings.Contains(err.Error(), "cached, default, default_v2") {
+		t.Errorf("New() error = %v, want registered names listed", err)
+	}
+}
+
+type fixedWorkerId int64
+
+func (f fixedWorkerId) NextWorkerId() int64 { return int64(f) }
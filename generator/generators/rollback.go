@@ -0,0 +1,48 @@
+package generators
+
+import (
+	"github.com/gomsr/atom-uid/generator"
+	"time"
+)
+
+// rollbackGuard applies a generator.RollbackPolicy with its tolerance
+type rollbackGuard struct {
+	policy    generator.RollbackPolicy
+	tolerance time.Duration
+}
+
+// after returns the timestamp to issue at: not below last, and above it once the sequence space of
+// last is exhausted. now reads clock in units; waits sleep through clock.
+func (r rollbackGuard) after(last int64, exhausted bool, unit time.Duration, now func() (int64, error), clock generator.Clock) (int64, error) {
+	for {
+		current, err := now()
+		if err != nil {
+			return 0, err
+		}
+		if current > last || (current == last && !exhausted) {
+			return current, nil
+		}
+		if current == last {
+			// wait for the clock to tick
+			if d := time.Unix(0, (last+1)*int64(unit)).Sub(clock.Now()); d > 0 {
+				clock.Sleep(d)
+			}
+			continue
+		}
+
+		drift := time.Duration(last-current) * unit
+		if r.policy == generator.RejectRollback || drift > r.tolerance {
+			return 0, &generator.ClockRollbackError{Drift: drift, Tolerance: r.tolerance}
+		}
+		if r.policy == generator.BorrowRollback {
+			if !exhausted {
+				return last, nil
+			}
+			if drift+unit > r.tolerance {
+				return 0, &generator.ClockRollbackError{Drift: drift + unit, Tolerance: r.tolerance}
+			}
+			return last + 1, nil
+		}
+		clock.Sleep(drift)
+	}
+}
@@ -0,0 +1,54 @@
+package generators
+
+import (
+	"errors"
+	"github.com/gomsr/atom-uid/generator"
+	"testing"
+	"time"
+)
+
+func TestRollbackGuard_After(t *testing.T) {
+	const last = 100
+	tests := []struct {
+		name      string
+		guard     rollbackGuard
+		clock     []int64 // successive readings
+		exhausted bool
+		want      int64
+		wantDrift time.Duration // of the ClockRollbackError, 0 for none
+	}{
+		{name: "clock ahead", clock: []int64{105}, want: 105},
+		{name: "same unit", clock: []int64{last}, want: last},
+		{name: "same unit exhausted waits for tick", clock: []int64{last, last, last + 1}, exhausted: true, want: last + 1},
+		{name: "reject", clock: []int64{98}, wantDrift: 2 * time.Millisecond},
+		{name: "beyond tolerance", guard: rollbackGuard{generator.WaitRollback, time.Millisecond}, clock: []int64{98}, wantDrift: 2 * time.Millisecond},
+		{name: "wait", guard: rollbackGuard{generator.WaitRollback, 5 * time.Millisecond}, clock: []int64{98, 101}, want: 101},
+		{name: "borrow last", guard: rollbackGuard{generator.BorrowRollback, 5 * time.Millisecond}, clock: []int64{98}, want: last},
+		{name: "borrow next", guard: rollbackGuard{generator.BorrowRollback, 5 * time.Millisecond}, clock: []int64{98}, exhausted: true, want: last + 1},
+		{name: "borrow past tolerance", guard: rollbackGuard{generator.BorrowRollback, 2 * time.Millisecond}, clock: []int64{98}, exhausted: true, wantDrift: 3 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readings := tt.clock
+			now := func() (int64, error) {
+				v := readings[0]
+				if len(readings) > 1 {
+					readings = readings[1:]
+				}
+				return v, nil
+			}
+
+			got, err := tt.guard.after(last, tt.exhausted, time.Millisecond, now, generator.NewManualClock(time.UnixMilli(last)))
+			var rollback *generator.ClockRollbackError
+			if tt.wantDrift != 0 {
+				if !errors.As(err, &rollback) || rollback.Drift != tt.wantDrift {
+					t.Fatalf("after() error = %v, want drift %s", err, tt.wantDrift)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("after() = %d, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
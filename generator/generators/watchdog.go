@@ -0,0 +1,75 @@
+package generators
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomsr/atom-uid/generator"
+	"github.com/gomsr/atom-uid/worker"
+	"sync/atomic"
+	"time"
+)
+
+// watchdog re-verifies worker ID ownership through the assigner every interval. Once a check
+// fails it keeps the failure, and the generator refuses to issue.
+type watchdog struct {
+	lost atomic.Pointer[generator.OwnershipLostError]
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startWatchdog returns nil, a watchdog that never fails, if interval is not positive or the
+// assigner cannot verify ownership.
+func startWatchdog(assigner worker.IdAssigner, workerId int64, interval time.Duration) *watchdog {
+	verifier, ok := assigner.(worker.Verifier)
+	if !ok || interval <= 0 {
+		return nil
+	}
+
+	w := &watchdog{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-w.stop:
+				return
+			case <-ticker.C:
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err := verifier.VerifyWorkerId(ctx, workerId)
+			cancel()
+			if err != nil {
+				fmt.Printf("Worker id %d ownership check failed, refusing UID generation: %v\n", workerId, err)
+				w.lost.Store(&generator.OwnershipLostError{WorkerId: workerId, Err: err})
+				return
+			}
+		}
+	}()
+	return w
+}
+
+// err returns the *generator.OwnershipLostError once ownership is lost.
+func (w *watchdog) err() error {
+	if w == nil {
+		return nil
+	}
+	if lost := w.lost.Load(); lost != nil {
+		return lost
+	}
+	return nil
+}
+
+func (w *watchdog) close() {
+	if w == nil {
+		return
+	}
+	select {
+	case <-w.stop:
+	default:
+		close(w.stop)
+	}
+	<-w.done
+}
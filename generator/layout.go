@@ -0,0 +1,191 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidLayout is wrapped by the errors of layouts that cannot issue UIDs.
+var ErrInvalidLayout = errors.New("invalid uid layout")
+
+const (
+	// MaxSafeInteger is JavaScript's Number.MAX_SAFE_INTEGER, the largest integer a JSON number
+	// keeps exactly in a browser.
+	MaxSafeInteger = 1<<53 - 1
+	// SafeBits is how many bits below the sign a layout may use for its UIDs to stay within MaxSafeInteger.
+	SafeBits = 53
+)
+
+// Layout is a UID layout with its epoch. It decodes, validates and composes UIDs offline, without
+// a generator, its ring buffer or a worker ID assigner.
+type Layout struct {
+	TimestampBits    int
+	WorkerIdBits     int
+	SequenceBits     int
+	DatacenterIdBits int           // high bits of the worker ID, 0 when the worker ID is not split
+	TimeUnit         time.Duration // 0 means time.Second
+	Epoch            time.Time
+	JSSafe           bool // keep every UID within MaxSafeInteger, rejecting layouts over SafeBits
+}
+
+// NewLayout builds a Layout with the epoch in EpochStrFormat and validates it at the current time, so
+// an epoch in the future or one whose timestamps already ran out is rejected. An empty epochStr means EpochStr.
+func NewLayout(timestampBits, workerIdBits, sequenceBits int, epochStr string) (Layout, error) {
+	if epochStr == "" {
+		epochStr = EpochStr
+	}
+	epoch, err := time.Parse(EpochStrFormat, epochStr)
+	if err != nil {
+		return Layout{}, fmt.Errorf("%w: epoch %q: %w", ErrInvalidLayout, epochStr, err)
+	}
+	l := Layout{TimestampBits: timestampBits, WorkerIdBits: workerIdBits, SequenceBits: sequenceBits, Epoch: epoch}
+	if err = l.ValidateAt(time.Now()); err != nil {
+		return Layout{}, err
+	}
+	return l, nil
+}
+
+// NewJSSafeLayout builds a 1+31+8+14 layout whose UIDs stay within MaxSafeInteger: 68 years of
+// seconds, 256 workers and 16384 UIDs per second each. An empty epochStr means EpochStr.
+func NewJSSafeLayout(epochStr string) (Layout, error) {
+	l, err := NewLayout(31, 8, 14, epochStr)
+	if err != nil {
+		return Layout{}, err
+	}
+	l.JSSafe = true
+	return l, nil
+}
+
+// Layout returns the allocator's layout counting from epochSeconds.
+func (b *BitsAllocator) Layout(epochSeconds int64) Layout {
+	return Layout{
+		TimestampBits:    b.timestampBits,
+		WorkerIdBits:     b.workerIdBits,
+		SequenceBits:     b.sequenceBits,
+		DatacenterIdBits: b.datacenterIdBits,
+		TimeUnit:         b.timeUnit,
+		Epoch:            time.Unix(epochSeconds, 0).UTC(),
+		JSSafe:           b.timestampBits+b.workerIdBits+b.sequenceBits <= SafeBits,
+	}
+}
+
+// Validate checks the bits fit a positive int64, or SafeBits if JSSafe, and the time unit divides a second.
+func (l Layout) Validate() error {
+	_, err := l.allocator()
+	return err
+}
+
+// ValidateAt is Validate, also requiring that the epoch has started by now and that the timestamp
+// bits have not run out yet.
+func (l Layout) ValidateAt(now time.Time) error {
+	_, err := l.NewAllocator(now)
+	return err
+}
+
+// NewAllocator validates the layout at now and builds its allocator. Errors wrap ErrInvalidLayout.
+func (l Layout) NewAllocator(now time.Time) (*BitsAllocator, error) {
+	b, err := l.allocator()
+	if err != nil {
+		return nil, err
+	}
+	if l.Epoch.After(now) {
+		return nil, fmt.Errorf("%w: epoch %s is in the future", ErrInvalidLayout, l.Epoch.Format(time.RFC3339))
+	}
+	if _, err = l.delta(b, now); err != nil {
+		return nil, fmt.Errorf("%w: epoch %s: the timestamp bits ran out at %s",
+			ErrInvalidLayout, l.Epoch.Format(time.RFC3339), l.Epoch.Add(b.GetLifetime()).Format(time.RFC3339))
+	}
+	return b, nil
+}
+
+// Check reports whether uid could have been issued under the layout: non-negative and without
+// bits above the layout.
+func (l Layout) Check(uid int64) error {
+	if err := l.Validate(); err != nil {
+		return err
+	}
+	if uid < 0 {
+		return fmt.Errorf("uid %d is negative", uid)
+	}
+	if bits := l.TimestampBits + l.WorkerIdBits + l.SequenceBits; bits < 63 && uid>>uint(bits) != 0 {
+		return fmt.Errorf("uid %d has bits above the %d of layout %s", uid, bits, l)
+	}
+	return nil
+}
+
+// Decode breaks uid into its fields, after Check.
+func (l Layout) Decode(uid int64) (UIDInfo, error) {
+	if err := l.Check(uid); err != nil {
+		return UIDInfo{}, err
+	}
+	b, _ := l.allocator()
+	return b.Decode(l.Epoch.Unix(), uid), nil
+}
+
+// Compose builds the UID of workerId and sequence at t, truncated to the time unit.
+func (l Layout) Compose(t time.Time, workerId, sequence int64) (int64, error) {
+	b, err := l.allocator()
+	if err != nil {
+		return 0, err
+	}
+	if t.Before(l.Epoch) {
+		return 0, fmt.Errorf("time %s is before the epoch %s", t, l.Epoch)
+	}
+	delta, err := l.delta(b, t)
+	if err != nil {
+		return 0, err
+	}
+	if workerId < 0 || workerId > b.maxWorkerId {
+		return 0, fmt.Errorf("worker id %d is outside [0, %d]", workerId, b.maxWorkerId)
+	}
+	if sequence < 0 || sequence > b.maxSequence {
+		return 0, fmt.Errorf("sequence %d is outside [0, %d]", sequence, b.maxSequence)
+	}
+	return b.Allocate(delta, workerId, sequence), nil
+}
+
+// String describes the bits as "sign+timestamp+worker+sequence", with the worker bits as
+// "(datacenter+machine)" when split and the time unit appended when it is not a second.
+func (l Layout) String() string {
+	worker := fmt.Sprint(l.WorkerIdBits)
+	if l.DatacenterIdBits > 0 {
+		worker = fmt.Sprintf("(%d+%d)", l.DatacenterIdBits, l.WorkerIdBits-l.DatacenterIdBits)
+	}
+	layout := fmt.Sprintf("1+%d+%s+%d", l.TimestampBits, worker, l.SequenceBits)
+	if l.TimeUnit != 0 && l.TimeUnit != time.Second {
+		layout += "/" + l.TimeUnit.String()
+	}
+	return layout
+}
+
+// delta returns the time units from the epoch to t, an error once they overflow the timestamp bits
+func (l Layout) delta(b *BitsAllocator, t time.Time) (int64, error) {
+	seconds, ups := t.Unix()-l.Epoch.Unix(), b.GetUnitsPerSecond()
+	delta := seconds*ups + int64(t.Nanosecond())/int64(b.timeUnit)
+	if seconds > b.maxDeltaSeconds/ups || delta > b.maxDeltaSeconds {
+		return 0, fmt.Errorf("time %s is beyond the %s lifetime of layout %s", t, b.GetLifetime(), l)
+	}
+	return delta, nil
+}
+
+func (l Layout) allocator() (*BitsAllocator, error) {
+	b, err := NewBitsAllocatorChecked(l.TimestampBits, l.WorkerIdBits, l.SequenceBits)
+	if err != nil {
+		return nil, err
+	}
+	if bits := l.TimestampBits + l.WorkerIdBits + l.SequenceBits; l.JSSafe && bits > SafeBits {
+		return nil, fmt.Errorf("%w: layout %s uses %d bits, JavaScript-safe UIDs fit in %d",
+			ErrInvalidLayout, l, bits, SafeBits)
+	}
+	if l.Epoch.IsZero() {
+		return nil, fmt.Errorf("%w: epoch is not set", ErrInvalidLayout)
+	}
+	if err = b.SetTimeUnit(l.TimeUnit); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLayout, err)
+	}
+	if err = b.SplitWorkerIdBits(l.DatacenterIdBits); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidLayout, err)
+	}
+	return b, nil
+}
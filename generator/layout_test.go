@@ -0,0 +1,106 @@
+package generator
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLayout(t *testing.T) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ms := Layout{TimestampBits: 41, WorkerIdBits: 10, SequenceBits: 12, DatacenterIdBits: 5, TimeUnit: time.Millisecond, Epoch: epoch}
+
+	at := epoch.Add(90*time.Minute + 1500*time.Microsecond)
+	uid, err := ms.Compose(at, 3<<5|7, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := ms.Decode(uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Time.Equal(at.Truncate(time.Millisecond)) || info.DatacenterId != 3 || info.MachineId != 7 || info.Sequence != 9 {
+		t.Errorf("Decode(Compose()) = %s", info)
+	}
+	if ms.String() != "1+41+(5+5)+12/1ms" {
+		t.Errorf("String() = %s", ms)
+	}
+
+	invalid := []Layout{
+		{TimestampBits: 41, WorkerIdBits: 11, SequenceBits: 12, Epoch: epoch},
+		{TimestampBits: 0, WorkerIdBits: 10, SequenceBits: 12, Epoch: epoch},
+		{TimestampBits: 41, WorkerIdBits: 10, SequenceBits: 12, TimeUnit: 3 * time.Millisecond, Epoch: epoch},
+		{TimestampBits: 41, WorkerIdBits: 10, SequenceBits: 12},
+	}
+	for _, l := range invalid {
+		if err := l.Validate(); !errors.Is(err, ErrInvalidLayout) {
+			t.Errorf("Validate() of %s = %v, want ErrInvalidLayout", l, err)
+		}
+	}
+
+	for _, c := range []struct {
+		at       time.Time
+		workerId int64
+		sequence int64
+	}{
+		{epoch.Add(-time.Second), 0, 0},
+		{epoch.Add(ms.TimeUnit << 41), 0, 0},
+		{at, 1 << 10, 0},
+		{at, 0, 1 << 12},
+	} {
+		if _, err := ms.Compose(c.at, c.workerId, c.sequence); err == nil {
+			t.Errorf("Compose(%s, %d, %d) succeeded", c.at, c.workerId, c.sequence)
+		}
+	}
+
+	if err := ms.ValidateAt(epoch.Add(-time.Hour)); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("ValidateAt() before the epoch = %v, want ErrInvalidLayout", err)
+	}
+	if err := ms.ValidateAt(epoch.Add(70 * 365 * 24 * time.Hour)); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("ValidateAt() after 70 years = %v, want ErrInvalidLayout", err)
+	}
+
+	narrow := Layout{TimestampBits: 28, WorkerIdBits: 10, SequenceBits: 12, Epoch: epoch}
+	for _, uid := range []int64{-1, 1 << 50} {
+		if _, err := narrow.Decode(uid); err == nil {
+			t.Errorf("Decode(%d) succeeded under %s", uid, narrow)
+		}
+	}
+}
+
+func TestNewJSSafeLayout(t *testing.T) {
+	l, err := NewJSSafeLayout("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	maxUID, err := l.Compose(l.Epoch.Add(time.Duration(1<<31-1)*time.Second), 1<<8-1, 1<<14-1)
+	if err != nil || maxUID != MaxSafeInteger {
+		t.Fatalf("largest uid = %d, %v, want MaxSafeInteger", maxUID, err)
+	}
+	info, err := l.Decode(maxUID)
+	if err != nil || info.WorkerId != 255 || info.Sequence != 1<<14-1 || info.Delta != 1<<31-1 {
+		t.Errorf("Decode(MaxSafeInteger) = %s, %v", info, err)
+	}
+	if _, err = l.Decode(MaxSafeInteger + 1); err == nil {
+		t.Error("Decode() of a uid past MaxSafeInteger succeeded")
+	}
+
+	l.SequenceBits++
+	if err = l.Validate(); !errors.Is(err, ErrInvalidLayout) {
+		t.Errorf("Validate() of a 54-bit JSSafe layout = %v, want ErrInvalidLayout", err)
+	}
+}
+
+func TestNewLayout_BadEpoch(t *testing.T) {
+	var parseErr *time.ParseError
+	if _, err := NewLayout(28, 22, 13, "2024-13-01"); !errors.Is(err, ErrInvalidLayout) || !errors.As(err, &parseErr) {
+		t.Errorf("NewLayout() error = %v, want ErrInvalidLayout wrapping the parse error", err)
+	}
+	future := time.Now().AddDate(1, 0, 0).Format(EpochStrFormat)
+	if l, err := NewLayout(28, 22, 13, future); !errors.Is(err, ErrInvalidLayout) || l != (Layout{}) {
+		t.Errorf("NewLayout(%s) = %+v, %v, want the future epoch rejected", future, l, err)
+	}
+	if l, err := NewJSSafeLayout(future); err == nil || l != (Layout{}) {
+		t.Errorf("NewJSSafeLayout(%s) = %+v, %v, want the zero Layout and an error", future, l, err)
+	}
+}
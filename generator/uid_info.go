@@ -0,0 +1,55 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// UIDInfo is a UID broken into its fields. Time is in UTC; DatacenterId and MachineId are set when
+// the layout splits the worker ID. In JSON the UID is a string, since 64-bit numbers lose precision
+// in JavaScript, and the other fields are numbers.
+type UIDInfo struct {
+	UID          int64     `json:"uid,string"`
+	Time         time.Time `json:"time"`
+	Delta        int64     `json:"delta"` // time units since the epoch
+	WorkerId     int64     `json:"workerId"`
+	DatacenterId int64     `json:"datacenterId"`
+	MachineId    int64     `json:"machineId"`
+	Sequence     int64     `json:"sequence"`
+	Layout       string    `json:"layout"` // e.g. "1+28+22+13", or "1+41+(5+5)+12/1ms"
+
+	split bool
+}
+
+// Decode breaks uid into its fields under the allocator's layout, counting from epochSeconds.
+func (b *BitsAllocator) Decode(epochSeconds, uid int64) UIDInfo {
+	info := UIDInfo{
+		UID:      uid,
+		Delta:    uid >> uint(b.timestampShift),
+		WorkerId: (uid >> uint(b.workerIdShift)) & b.maxWorkerId,
+		Sequence: uid & b.maxSequence,
+		Layout:   b.Layout(epochSeconds).String(),
+		split:    b.datacenterIdBits > 0,
+	}
+	// whole seconds and the rest apart, so wide timestamp fields do not overflow a time.Duration
+	perSecond := b.GetUnitsPerSecond()
+	info.Time = time.Unix(epochSeconds+info.Delta/perSecond, int64(time.Duration(info.Delta%perSecond)*b.timeUnit)).UTC()
+	info.DatacenterId, info.MachineId = b.SplitWorkerId(info.WorkerId)
+	return info
+}
+
+// JSON encodes the fields as a JSON object.
+func (i UIDInfo) JSON() string {
+	data, _ := json.Marshal(i)
+	return string(data)
+}
+
+func (i UIDInfo) String() string {
+	worker := fmt.Sprint(i.WorkerId)
+	if i.split {
+		worker = fmt.Sprintf("%d (datacenter %d, machine %d)", i.WorkerId, i.DatacenterId, i.MachineId)
+	}
+	return fmt.Sprintf("uid %d: time %s, worker %s, sequence %d, layout %s",
+		i.UID, i.Time.Format(time.RFC3339Nano), worker, i.Sequence, i.Layout)
+}
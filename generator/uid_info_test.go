@@ -0,0 +1,53 @@
+package generator
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestBitsAllocator_Decode(t *testing.T) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	b := NewBitsAllocator(41, 10, 12)
+	if err := b.SetTimeUnit(time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.SplitWorkerIdBits(5); err != nil {
+		t.Fatal(err)
+	}
+
+	uid := b.Allocate(1500, 3<<5|7, 42)
+	info := b.Decode(epoch, uid)
+	want := UIDInfo{UID: uid, Time: time.Date(2024, 1, 1, 0, 0, 1, 5e8, time.UTC), Delta: 1500, WorkerId: 103,
+		DatacenterId: 3, MachineId: 7, Sequence: 42, Layout: "1+41+(5+5)+12/1ms", split: true}
+	if info != want {
+		t.Errorf("Decode() = %+v, want %+v", info, want)
+	}
+
+	var decoded UIDInfo
+	if err := json.Unmarshal([]byte(info.JSON()), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.UID != uid || !decoded.Time.Equal(want.Time) || decoded.Sequence != 42 {
+		t.Errorf("JSON() = %s does not round-trip", info.JSON())
+	}
+}
+
+func TestBitsAllocator_DecodeMaxDelta(t *testing.T) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	for _, tt := range []struct {
+		unit time.Duration
+		want time.Time
+	}{
+		{time.Second, time.Unix(epoch+1<<57-1, 0)},
+		{time.Millisecond, time.Unix(epoch+(1<<57-1)/1000, 871*int64(time.Millisecond))},
+	} {
+		b := NewBitsAllocator(57, 3, 3)
+		if err := b.SetTimeUnit(tt.unit); err != nil {
+			t.Fatal(err)
+		}
+		if info := b.Decode(epoch, b.Allocate(1<<57-1, 0, 0)); !info.Time.Equal(tt.want) {
+			t.Errorf("Decode() of the largest delta in %s = %s, want %s", tt.unit, info.Time, tt.want.UTC())
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package utilu
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LookupName returns the index of text in names, ignoring case and surrounding space. The index
+// itself is accepted as decimal text, so enums configured by their former numeric values still parse.
+func LookupName[T ~string](names []T, text string) (int, bool) {
+	name := strings.ToLower(strings.TrimSpace(text))
+	for i, n := range names {
+		if name == string(n) || name == strconv.Itoa(i) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// UnmarshalJSONText decodes a JSON string, or a number as its decimal text, with u.UnmarshalText.
+// what names the value in the error.
+func UnmarshalJSONText(data []byte, what string, u encoding.TextUnmarshaler) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		var number json.Number
+		if json.Unmarshal(data, &number) != nil {
+			return fmt.Errorf("%s must be a string: %s", what, data)
+		}
+		text = number.String()
+	}
+	return u.UnmarshalText([]byte(text))
+}
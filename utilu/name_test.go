@@ -0,0 +1,46 @@
+package utilu
+
+import (
+	"strconv"
+	"testing"
+)
+
+type level int
+
+func (l *level) UnmarshalText(text []byte) error {
+	i, ok := LookupName([]string{"low", "high"}, string(text))
+	if !ok {
+		return strconv.ErrSyntax
+	}
+	*l = level(i)
+	return nil
+}
+
+func TestLookupName(t *testing.T) {
+	names := []string{"reject", "wait", "borrow"}
+	for text, want := range map[string]int{"wait": 1, " Borrow ": 2, "0": 0, "2": 2} {
+		if got, ok := LookupName(names, text); !ok || got != want {
+			t.Errorf("LookupName(%q) = %d, %t, want %d", text, got, ok, want)
+		}
+	}
+	for _, text := range []string{"", "skew", "3", "-1"} {
+		if got, ok := LookupName(names, text); ok {
+			t.Errorf("LookupName(%q) = %d, want not found", text, got)
+		}
+	}
+}
+
+func TestUnmarshalJSONText(t *testing.T) {
+	for data, want := range map[string]level{`"HIGH"`: 1, `0`: 0, `"1"`: 1} {
+		var l level
+		if err := UnmarshalJSONText([]byte(data), "level", &l); err != nil || l != want {
+			t.Errorf("UnmarshalJSONText(%s) = %d, %v, want %d", data, l, err, want)
+		}
+	}
+	for _, data := range []string{`true`, `"mid"`, `2`} {
+		var l level
+		if err := UnmarshalJSONText([]byte(data), "level", &l); err == nil {
+			t.Errorf("UnmarshalJSONText(%s) = %d, want an error", data, l)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gomsr/atom-uid/worker/workers"
+	"sync"
+)
+
+// ChainEntry is one named source of a Chain.
+type ChainEntry struct {
+	Name     string
+	Assigner IdAssigner
+}
+
+// Chain asks its entries in order and returns the first worker ID assigned. The LocalAssigner only
+// keeps processes on one host apart, so the chain refuses to fall through to it unless AllowLocal.
+type Chain struct {
+	Entries    []ChainEntry
+	AllowLocal bool
+
+	mu       sync.Mutex
+	source   string
+	assigner IdAssigner
+}
+
+// NewChain builds a Chain from the registered assigners of types, in order.
+func NewChain(allowLocal bool, types ...Type) (*Chain, error) {
+	chain := &Chain{AllowLocal: allowLocal}
+	for _, t := range types {
+		assigner, err := t.Instance()
+		if err != nil {
+			return nil, err
+		}
+		chain.Entries = append(chain.Entries, ChainEntry{Name: string(t.normalize()), Assigner: assigner})
+	}
+	return chain, nil
+}
+
+// Source returns the name of the entry that provided the last worker ID.
+func (c *Chain) Source() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.source
+}
+
+func (c *Chain) SetMaxWorkerId(maxWorkerId int64) {
+	for _, e := range c.Entries {
+		if b, ok := e.Assigner.(Bounded); ok {
+			b.SetMaxWorkerId(maxWorkerId)
+		}
+	}
+}
+
+func (c *Chain) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	if len(c.Entries) == 0 {
+		return 0, errors.New("assigner chain is empty")
+	}
+
+	var errs []error
+	for _, e := range c.Entries {
+		if err := ctx.Err(); err != nil {
+			return 0, errors.Join(append(errs, err)...)
+		}
+		if _, local := e.Assigner.(*workers.LocalAssigner); local && !c.AllowLocal {
+			errs = append(errs, fmt.Errorf("%s: refusing host-local fallback, set AllowLocal to permit it", e.Name))
+			continue
+		}
+
+		wid, err := e.Assigner.NextWorkerIdContext(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Name, err))
+			continue
+		}
+
+		c.mu.Lock()
+		c.source, c.assigner = e.Name, e.Assigner
+		c.mu.Unlock()
+		fmt.Printf("Assigned worker id %d from %s\n", wid, e.Name)
+		return wid, nil
+	}
+
+	return 0, fmt.Errorf("no assigner in chain could assign a worker id: %w", errors.Join(errs...))
+}
+
+// ReleaseWorkerId releases the ID through the entry that assigned it.
+func (c *Chain) ReleaseWorkerId(ctx context.Context, workerId, lastTimestamp int64) error {
+	c.mu.Lock()
+	assigner := c.assigner
+	c.mu.Unlock()
+
+	if assigner == nil {
+		return nil
+	}
+	return Release(ctx, assigner, workerId, lastTimestamp)
+}
+
+// FenceTimestamp returns the fence of the entry that assigned the ID.
+func (c *Chain) FenceTimestamp() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.assigner == nil {
+		return 0
+	}
+	return Fence(c.assigner)
+}
+
+// VerifyWorkerId verifies the ID through the entry that assigned it.
+func (c *Chain) VerifyWorkerId(ctx context.Context, workerId int64) error {
+	c.mu.Lock()
+	assigner := c.assigner
+	c.mu.Unlock()
+
+	if assigner == nil {
+		return fmt.Errorf("assigner chain has not assigned worker id %d", workerId)
+	}
+	return Verify(ctx, assigner, workerId)
+}
@@ -0,0 +1,45 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/gomsr/atom-uid/worker/workers"
+	"testing"
+)
+
+type stubAssigner struct {
+	id  int64
+	err error
+}
+
+func (s stubAssigner) NextWorkerIdContext(context.Context) (int64, error) { return s.id, s.err }
+
+func TestChain_NextWorkerIdContext(t *testing.T) {
+	chain := &Chain{Entries: []ChainEntry{
+		{Name: "cloudflare", Assigner: stubAssigner{err: errors.New("unreachable")}},
+		{Name: "db", Assigner: stubAssigner{id: 42}},
+	}}
+	if id, err := chain.NextWorkerIdContext(context.Background()); err != nil || id != 42 {
+		t.Fatalf("NextWorkerIdContext() = %d, %v, want 42", id, err)
+	}
+	if chain.Source() != "db" {
+		t.Errorf("Source() = %q, want db", chain.Source())
+	}
+}
+
+func TestChain_RefusesLocal(t *testing.T) {
+	entries := []ChainEntry{
+		{Name: "cloudflare", Assigner: stubAssigner{err: errors.New("unreachable")}},
+		{Name: "local", Assigner: workers.NewLocalAssigner(t.TempDir(), 7)},
+	}
+
+	if _, err := (&Chain{Entries: entries}).NextWorkerIdContext(context.Background()); err == nil {
+		t.Fatal("chain fell through to the local assigner")
+	}
+
+	chain := &Chain{Entries: entries, AllowLocal: true}
+	if id, err := chain.NextWorkerIdContext(context.Background()); err != nil || id != 0 || chain.Source() != "local" {
+		t.Fatalf("NextWorkerIdContext() = %d, %v from %q, want 0 from local", id, err, chain.Source())
+	}
+	_ = entries[1].Assigner.(*workers.LocalAssigner).Close()
+}
@@ -0,0 +1,14 @@
+package worker
+
+import "context"
+
+// Checkpoint persists a high-water mark per worker ID: a unix millisecond time above every
+// timestamp issued with the ID. A generator restarted with the ID does not issue below it, even
+// when the clock is behind. Assigners that can keep the mark next to the ID implement it.
+type Checkpoint interface {
+	// LoadHighWater returns the mark of workerId, 0 if none was saved.
+	LoadHighWater(ctx context.Context, workerId int64) (int64, error)
+	// SaveHighWater replaces the mark of workerId. Generators raise it while issuing and lower it on
+	// Close to just above the last timestamp issued.
+	SaveHighWater(ctx context.Context, workerId, mark int64) error
+}
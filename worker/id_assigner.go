@@ -1,33 +1,60 @@
 package worker
 
-import "github.com/gomsr/atom-uid/worker/workers"
+import (
+	"context"
+	"fmt"
+)
 
-type Type uint
+// Type names a registered IdAssigner, e.g. "local", "db" or "cloudflare".
+type Type string
 
 const (
-	LocalWorkerId Type = iota
-	DbWorkerId
-	CloudflareWorkerId
+	LocalWorkerId      Type = "local"
+	DbWorkerId         Type = "db"
+	CloudflareWorkerId Type = "cloudflare"
+	EnvWorkerId        Type = "env"
+	NetWorkerId        Type = "net"
+	RedisWorkerId      Type = "redis"
+	StaticWorkerId     Type = "static"
 )
 
 // IdAssigner defines an interface for assigning worker IDs.
 type IdAssigner interface {
 
-	// NextWorkerId assigns a worker ID for the DefaultUidGenerator.
-	// Returns the assigned worker ID.
+	// NextWorkerIdContext assigns a worker ID for the DefaultUidGenerator.
+	// Returns the assigned worker ID or an error if no ID could be assigned.
+	NextWorkerIdContext(ctx context.Context) (int64, error)
+}
+
+// Bounded is implemented by assigners that can pick an ID within the generator's worker budget.
+// The generator calls SetMaxWorkerId with the layout's max worker ID before asking for an ID.
+type Bounded interface {
+	SetMaxWorkerId(maxWorkerId int64)
+}
+
+// LegacyIdAssigner is the former contract, which could only report failure by panicking.
+type LegacyIdAssigner interface {
 	NextWorkerId() int64
 }
 
-func (c Type) Instance() IdAssigner {
-	var assigner IdAssigner
-	switch c {
-	case DbWorkerId:
-		assigner = &workers.DbAssigner{}
-	case CloudflareWorkerId:
-		assigner = &workers.CloudflareAssigner{}
-	default:
-		assigner = &workers.LocalAssigner{}
+// Legacy adapts a LegacyIdAssigner to IdAssigner, turning its panic into an error.
+func Legacy(assigner LegacyIdAssigner) IdAssigner {
+	return &legacyAssigner{assigner}
+}
+
+type legacyAssigner struct {
+	LegacyIdAssigner
+}
+
+func (c *legacyAssigner) NextWorkerIdContext(ctx context.Context) (id int64, err error) {
+	if err = ctx.Err(); err != nil {
+		return 0, err
 	}
+	defer func() {
+		if r := recover(); r != nil {
+			id, err = 0, fmt.Errorf("assign worker id: %v", r)
+		}
+	}()
 
-	return assigner
+	return c.NextWorkerId(), nil
 }
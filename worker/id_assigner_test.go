@@ -1,10 +1,38 @@
 package worker
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
 
 func TestType_Instance(t *testing.T) {
-	fmt.Println(LocalWorkerId.Instance().NextWorkerId())
+	assigner, err := LocalWorkerId.Instance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(assigner.NextWorkerIdContext(context.Background()))
+}
+
+type panicAssigner struct{}
+
+func (panicAssigner) NextWorkerId() int64 { panic("Could not assign worker id") }
+
+type fixedAssigner int64
+
+func (f fixedAssigner) NextWorkerId() int64 { return int64(f) }
+
+func TestLegacy(t *testing.T) {
+	if id, err := Legacy(fixedAssigner(7)).NextWorkerIdContext(context.Background()); err != nil || id != 7 {
+		t.Errorf("NextWorkerIdContext() = %d, %v, want 7, nil", id, err)
+	}
+	if _, err := Legacy(panicAssigner{}).NextWorkerIdContext(context.Background()); err == nil {
+		t.Error("expected panic to be returned as error")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Legacy(fixedAssigner(7)).NextWorkerIdContext(ctx); err == nil {
+		t.Error("expected canceled context error")
+	}
 }
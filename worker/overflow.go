@@ -0,0 +1,89 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"github.com/gomsr/atom-uid/utilu"
+	"strconv"
+	"strings"
+)
+
+// OverflowPolicy decides what happens to an assigned worker ID outside [0, maxWorkerId].
+type OverflowPolicy uint
+
+const (
+	// RejectOverflow fails the assignment, so no UID with a corrupted timestamp can be emitted.
+	RejectOverflow OverflowPolicy = iota
+	// WrapOverflow remaps the ID to workerId mod (maxWorkerId+1). Two nodes may then share an ID,
+	// so only use it when the assigner hands out IDs far apart in time (e.g. use-once counters).
+	WrapOverflow
+)
+
+// overflowPolicies are the config names of the policies, indexed by value.
+var overflowPolicies = []string{"reject", "wrap"}
+
+// UnmarshalText accepts a policy name, or its former numeric value. Empty text is RejectOverflow.
+func (p *OverflowPolicy) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		*p = RejectOverflow
+		return nil
+	}
+	if i, ok := utilu.LookupName(overflowPolicies, string(text)); ok {
+		*p = OverflowPolicy(i)
+		return nil
+	}
+	return fmt.Errorf("unknown worker id overflow policy %q, want one of %s", text, strings.Join(overflowPolicies, ", "))
+}
+
+// UnmarshalJSON also accepts the former numeric values.
+func (p *OverflowPolicy) UnmarshalJSON(data []byte) error {
+	return utilu.UnmarshalJSONText(data, "worker id overflow policy", p)
+}
+
+// String returns the config name of the policy.
+func (p OverflowPolicy) String() string {
+	if int(p) < len(overflowPolicies) {
+		return overflowPolicies[p]
+	}
+	return strconv.FormatUint(uint64(p), 10)
+}
+
+// MarshalText writes the policy name, so configs round-trip through UnmarshalText.
+func (p OverflowPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// OutOfRangeError reports a worker ID that does not fit the layout's worker bits.
+type OutOfRangeError struct {
+	WorkerId    int64
+	MaxWorkerId int64
+}
+
+func (e *OutOfRangeError) Error() string {
+	return fmt.Sprintf("worker id %d out of range [0, %d]", e.WorkerId, e.MaxWorkerId)
+}
+
+// Apply checks workerId against maxWorkerId under the policy.
+func (p OverflowPolicy) Apply(workerId, maxWorkerId int64) (int64, error) {
+	if workerId >= 0 && workerId <= maxWorkerId {
+		return workerId, nil
+	}
+	if p == WrapOverflow && maxWorkerId >= 0 {
+		n := maxWorkerId + 1
+		return (workerId%n + n) % n, nil
+	}
+	return 0, &OutOfRangeError{WorkerId: workerId, MaxWorkerId: maxWorkerId}
+}
+
+// Assign tells a Bounded assigner the budget, asks it for a worker ID and applies the policy.
+func Assign(ctx context.Context, assigner IdAssigner, maxWorkerId int64, policy OverflowPolicy) (int64, error) {
+	if b, ok := assigner.(Bounded); ok {
+		b.SetMaxWorkerId(maxWorkerId)
+	}
+
+	wid, err := assigner.NextWorkerIdContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return policy.Apply(wid, maxWorkerId)
+}
@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestOverflowPolicy_Apply(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   OverflowPolicy
+		workerId int64
+		want     int64
+		wantErr  bool
+	}{
+		{name: "reject in range", policy: RejectOverflow, workerId: 7, want: 7},
+		{name: "reject too large", policy: RejectOverflow, workerId: 511, wantErr: true},
+		{name: "reject negative", policy: RejectOverflow, workerId: -1, wantErr: true},
+		{name: "wrap in range", policy: WrapOverflow, workerId: 5, want: 5},
+		{name: "wrap too large", policy: WrapOverflow, workerId: 511, want: 7},
+		{name: "wrap negative", policy: WrapOverflow, workerId: -1, want: 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.policy.Apply(tt.workerId, 7)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Apply() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+			var rangeErr *OutOfRangeError
+			if tt.wantErr && !errors.As(err, &rangeErr) {
+				t.Errorf("Apply() error = %T, want *OutOfRangeError", err)
+			}
+		})
+	}
+}
+
+type boundedAssigner struct{ max int64 }
+
+func (b *boundedAssigner) SetMaxWorkerId(maxWorkerId int64) { b.max = maxWorkerId }
+
+func (b *boundedAssigner) NextWorkerIdContext(context.Context) (int64, error) { return b.max, nil }
+
+func TestAssign_Bounded(t *testing.T) {
+	if id, err := Assign(context.Background(), &boundedAssigner{}, 7, RejectOverflow); err != nil || id != 7 {
+		t.Errorf("Assign() = %d, %v, want the budget passed to the assigner", id, err)
+	}
+}
+
+func TestOverflowPolicy_UnmarshalJSON(t *testing.T) {
+	var conf struct {
+		Policies []OverflowPolicy `json:"policies"`
+	}
+	if err := json.Unmarshal([]byte(`{"policies": ["Wrap", "reject", "", 1]}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	want := []OverflowPolicy{WrapOverflow, RejectOverflow, RejectOverflow, WrapOverflow}
+	for i, p := range conf.Policies {
+		if p != want[i] {
+			t.Errorf("policies[%d] = %d, want %d", i, p, want[i])
+		}
+	}
+
+	var p OverflowPolicy
+	if err := json.Unmarshal([]byte(`"clamp"`), &p); err == nil {
+		t.Errorf("Unmarshal(clamp) = %d, want an error", p)
+	}
+
+	data, _ := json.Marshal(conf)
+	if string(data) != `{"policies":["wrap","reject","reject","wrap"]}` {
+		t.Errorf("marshalled %s", data)
+	}
+}
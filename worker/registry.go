@@ -0,0 +1,111 @@
+package worker
+
+import (
+	"fmt"
+	"github.com/gomsr/atom-uid/utilu"
+	"github.com/gomsr/atom-uid/worker/workers"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a new IdAssigner each time it is called.
+type Factory func() IdAssigner
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[Type]Factory{}
+
+	// legacyTypes are the types named by the former uint constants, still found in numeric configs, indexed by value.
+	legacyTypes = []Type{LocalWorkerId, DbWorkerId, CloudflareWorkerId}
+)
+
+func init() {
+	Register(LocalWorkerId, func() IdAssigner { return &workers.LocalAssigner{} })
+	Register(DbWorkerId, func() IdAssigner { return &workers.DbAssigner{} })
+	Register(CloudflareWorkerId, func() IdAssigner { return &workers.CloudflareAssigner{} })
+	Register(EnvWorkerId, func() IdAssigner { return &workers.EnvAssigner{} })
+	Register(NetWorkerId, func() IdAssigner { return &workers.NetAssigner{} })
+	Register(RedisWorkerId, func() IdAssigner { return workers.NewRedisAssignerFromEnv() })
+	Register(StaticWorkerId, func() IdAssigner { return &workers.StaticAssigner{} })
+}
+
+// Register makes an assigner available by name. It panics if the name is empty or already registered.
+func Register(t Type, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	t = t.normalize()
+	if t == "" || factory == nil {
+		panic("worker: Register with empty type or nil factory")
+	}
+	if _, dup := registry[t]; dup {
+		panic("worker: Register called twice for type " + string(t))
+	}
+	registry[t] = factory
+}
+
+// Replace registers factory as t whether or not the name is taken, e.g. to bind a configured
+// *sql.DB to DbWorkerId:
+//
+//	worker.Replace(worker.DbWorkerId, func() worker.IdAssigner { return workers.NewDbAssigner(db, workers.MySQL) })
+func Replace(t Type, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	t = t.normalize()
+	if t == "" || factory == nil {
+		panic("worker: Replace with empty type or nil factory")
+	}
+	registry[t] = factory
+}
+
+// Types returns the registered assigner names, sorted.
+func Types() []Type {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]Type, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Instance creates the assigner registered as c. The empty type is LocalWorkerId.
+func (c Type) Instance() (IdAssigner, error) {
+	t := c.normalize()
+	if t == "" {
+		t = LocalWorkerId
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[t]
+	registryMu.RUnlock()
+	if !ok {
+		names := make([]string, 0)
+		for _, registered := range Types() {
+			names = append(names, string(registered))
+		}
+		return nil, fmt.Errorf("unknown worker id assigner %q, registered: %s", string(c), strings.Join(names, ", "))
+	}
+	return factory(), nil
+}
+
+func (c Type) normalize() Type {
+	if i, ok := utilu.LookupName(legacyTypes, string(c)); ok {
+		return legacyTypes[i]
+	}
+	return Type(strings.ToLower(strings.TrimSpace(string(c))))
+}
+
+func (c *Type) UnmarshalText(text []byte) error {
+	*c = Type(text).normalize()
+	return nil
+}
+
+// UnmarshalJSON also accepts the former numeric values.
+func (c *Type) UnmarshalJSON(data []byte) error {
+	return utilu.UnmarshalJSONText(data, "worker type", c)
+}
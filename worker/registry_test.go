@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gomsr/atom-uid/worker/workers"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var registerOnce sync.Once
+
+func TestRegister(t *testing.T) {
+	registerOnce.Do(func() {
+		Register("fixed-test", func() IdAssigner { return stubAssigner{id: 9} })
+	})
+
+	assigner, err := Type("Fixed-Test").Instance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := assigner.NextWorkerIdContext(context.Background()); id != 9 {
+		t.Errorf("NextWorkerIdContext() = %d, want 9", id)
+	}
+
+	_, err = Type("zookeeper").Instance()
+	if err == nil || !strings.Contains(err.Error(), "cloudflare, db, env, fixed-test, local, net") {
+		t.Errorf("Instance() error = %v, want registered names listed", err)
+	}
+}
+
+func TestType_UnmarshalJSON(t *testing.T) {
+	var conf struct {
+		IdAssigner Type   `json:"id_assigner"`
+		Chain      []Type `json:"chain"`
+	}
+	if err := json.Unmarshal([]byte(`{"id_assigner": 2, "chain": ["DB", "local"]}`), &conf); err != nil {
+		t.Fatal(err)
+	}
+	if conf.IdAssigner != CloudflareWorkerId || conf.Chain[0] != DbWorkerId || conf.Chain[1] != LocalWorkerId {
+		t.Errorf("unmarshalled %+v", conf)
+	}
+
+	data, _ := json.Marshal(conf)
+	if string(data) != `{"id_assigner":"cloudflare","chain":["db","local"]}` {
+		t.Errorf("marshalled %s", data)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	assigner, _ := DbWorkerId.Instance()
+	if _, err := assigner.NextWorkerIdContext(context.Background()); err == nil || !strings.Contains(err.Error(), "sql.DB is nil") {
+		t.Fatalf("unbound db assigner error = %v", err)
+	}
+
+	bound := workers.NewDbAssigner(nil, workers.MySQL)
+	Replace(DbWorkerId, func() IdAssigner { return bound })
+	defer Replace(DbWorkerId, func() IdAssigner { return &workers.DbAssigner{} })
+	if assigner, _ = Type("DB").Instance(); assigner != bound {
+		t.Errorf("Instance() = %v, want the replaced assigner", assigner)
+	}
+}
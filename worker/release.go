@@ -0,0 +1,31 @@
+package worker
+
+import "context"
+
+// Releaser is implemented by assigners that can give their worker ID back on shutdown.
+// lastTimestamp is the last timestamp (unix seconds) the process issued with the ID.
+type Releaser interface {
+	ReleaseWorkerId(ctx context.Context, workerId, lastTimestamp int64) error
+}
+
+// Fencer is implemented by assigners that know the last timestamp the previous owner of the
+// assigned ID issued. The generator must not issue at or below it.
+type Fencer interface {
+	FenceTimestamp() int64
+}
+
+// Release gives workerId back if the assigner is a Releaser.
+func Release(ctx context.Context, assigner IdAssigner, workerId, lastTimestamp int64) error {
+	if r, ok := assigner.(Releaser); ok {
+		return r.ReleaseWorkerId(ctx, workerId, lastTimestamp)
+	}
+	return nil
+}
+
+// Fence returns the assigner's fence timestamp, or 0 if it is not a Fencer.
+func Fence(assigner IdAssigner) int64 {
+	if f, ok := assigner.(Fencer); ok {
+		return f.FenceTimestamp()
+	}
+	return 0
+}
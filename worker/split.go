@@ -0,0 +1,100 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Split composes the worker ID from two sub-fields, as in classic Snowflake: a datacenter ID in
+// the high bits and a machine ID in the low MachineBits, each from its own assigner.
+type Split struct {
+	Datacenter  IdAssigner
+	Machine     IdAssigner
+	MachineBits int
+
+	maxWorkerId int64
+}
+
+// NewSplit builds a Split with machineBits low bits for the machine ID.
+func NewSplit(datacenter, machine IdAssigner, machineBits int) *Split {
+	return &Split{Datacenter: datacenter, Machine: machine, MachineBits: machineBits}
+}
+
+// SetMaxWorkerId passes each Bounded sub-assigner the budget of its own sub-field.
+func (c *Split) SetMaxWorkerId(maxWorkerId int64) {
+	c.maxWorkerId = maxWorkerId
+	if b, ok := c.Datacenter.(Bounded); ok {
+		b.SetMaxWorkerId(maxWorkerId >> uint(c.MachineBits))
+	}
+	if b, ok := c.Machine.(Bounded); ok {
+		b.SetMaxWorkerId(maxWorkerId & c.maxMachineId())
+	}
+}
+
+func (c *Split) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	if c.Datacenter == nil || c.Machine == nil {
+		return 0, errors.New("split assigner: datacenter and machine assigners are required")
+	}
+	if c.MachineBits <= 0 || c.MachineBits >= 63 {
+		return 0, fmt.Errorf("split assigner: machine bits %d must be in [1, 62]", c.MachineBits)
+	}
+
+	dc, err := c.Datacenter.NextWorkerIdContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("datacenter id: %w", err)
+	}
+	machine, err := c.Machine.NextWorkerIdContext(ctx)
+	if err != nil {
+		return 0, errors.Join(fmt.Errorf("machine id: %w", err), abandon(ctx, c.Datacenter, dc))
+	}
+	maxDatacenterId := int64(1)<<uint(63-c.MachineBits) - 1
+	if c.maxWorkerId > 0 {
+		maxDatacenterId = c.maxWorkerId >> uint(c.MachineBits)
+	}
+	if dc < 0 || dc > maxDatacenterId {
+		err = fmt.Errorf("datacenter id: %w", &OutOfRangeError{WorkerId: dc, MaxWorkerId: maxDatacenterId})
+	} else if machine < 0 || machine > c.maxMachineId() {
+		err = fmt.Errorf("machine id: %w", &OutOfRangeError{WorkerId: machine, MaxWorkerId: c.maxMachineId()})
+	}
+	if err != nil {
+		return 0, errors.Join(err, abandon(ctx, c.Machine, machine), abandon(ctx, c.Datacenter, dc))
+	}
+	return dc<<uint(c.MachineBits) | machine, nil
+}
+
+// abandon gives back a sub-field ID assigned for a worker ID that then failed, keeping its fence
+func abandon(ctx context.Context, assigner IdAssigner, id int64) error {
+	return Release(context.WithoutCancel(ctx), assigner, id, Fence(assigner))
+}
+
+// ReleaseWorkerId releases both sub-field IDs.
+func (c *Split) ReleaseWorkerId(ctx context.Context, workerId, lastTimestamp int64) error {
+	dc, machine := workerId>>uint(c.MachineBits), workerId&c.maxMachineId()
+	return errors.Join(Release(ctx, c.Machine, machine, lastTimestamp), Release(ctx, c.Datacenter, dc, lastTimestamp))
+}
+
+// FenceTimestamp returns the later fence of the two sub-assigners.
+func (c *Split) FenceTimestamp() int64 {
+	return max(Fence(c.Datacenter), Fence(c.Machine))
+}
+
+// VerifyWorkerId verifies both sub-field IDs.
+func (c *Split) VerifyWorkerId(ctx context.Context, workerId int64) error {
+	dc, machine := workerId>>uint(c.MachineBits), workerId&c.maxMachineId()
+	return errors.Join(Verify(ctx, c.Datacenter, dc), Verify(ctx, c.Machine, machine))
+}
+
+func (c *Split) maxMachineId() int64 {
+	return 1<<uint(c.MachineBits) - 1
+}
+
+// Fixed is an assigner that always returns id, for a sub-field set by hand.
+type Fixed int64
+
+func (c Fixed) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return int64(c), nil
+}
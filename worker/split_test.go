@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/gomsr/atom-uid/worker/workers"
+	"testing"
+)
+
+func TestSplit_NextWorkerIdContext(t *testing.T) {
+	tests := []struct {
+		name       string
+		datacenter IdAssigner
+		machine    IdAssigner
+		want       int64
+		wantErr    bool
+	}{
+		{name: "compose", datacenter: Fixed(3), machine: stubAssigner{id: 5}, want: 3<<5 | 5},
+		{name: "machine out of range", datacenter: Fixed(3), machine: stubAssigner{id: 32}, wantErr: true},
+		{name: "datacenter out of range", datacenter: Fixed(32), machine: stubAssigner{id: 5}, wantErr: true},
+		{name: "datacenter error", datacenter: stubAssigner{err: errors.New("down")}, machine: Fixed(1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Assign(context.Background(), NewSplit(tt.datacenter, tt.machine, 5), 1<<10-1, RejectOverflow)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("Assign() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSplit_BoundsMachineAssigner(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 4; i++ {
+		if _, err := workers.NewLocalAssigner(dir, 3).NextWorkerIdContext(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the local assigner's own default range is much larger than the 2 machine bits
+	split := NewSplit(Fixed(1), &workers.LocalAssigner{Dir: dir}, 2)
+	if _, err := Assign(context.Background(), split, 1<<4-1, RejectOverflow); !errors.Is(err, workers.ErrNoFreeWorkerId) {
+		t.Fatalf("Assign() error = %v, want ErrNoFreeWorkerId within the machine budget", err)
+	}
+}
+
+func TestSplit_ReleasesOnFailure(t *testing.T) {
+	dir := t.TempDir()
+	for _, machine := range []IdAssigner{stubAssigner{err: errors.New("down")}, stubAssigner{id: 32}} {
+		for i := 0; i < 3; i++ {
+			_, err := Assign(context.Background(), NewSplit(&workers.LocalAssigner{Dir: dir}, machine, 5), 1<<7-1, RejectOverflow)
+			if err == nil {
+				t.Fatal("Assign() succeeded with a failing machine assigner")
+			}
+		}
+	}
+
+	// the four datacenter slots of 2 bits are all free again
+	for i := int64(0); i < 4; i++ {
+		if id, err := workers.NewLocalAssigner(dir, 3).NextWorkerIdContext(context.Background()); err != nil || id != i {
+			t.Fatalf("datacenter slot %d = %d, %v, want it released", i, id, err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package worker
+
+import "context"
+
+// Verifier is implemented by assigners that can check with their backend that the process still
+// owns the worker ID it was assigned. A non-nil error means ownership is lost or was never held.
+type Verifier interface {
+	VerifyWorkerId(ctx context.Context, workerId int64) error
+}
+
+// Verify checks ownership of workerId if the assigner is a Verifier.
+func Verify(ctx context.Context, assigner IdAssigner, workerId int64) error {
+	if v, ok := assigner.(Verifier); ok {
+		return v.VerifyWorkerId(ctx, workerId)
+	}
+	return nil
+}
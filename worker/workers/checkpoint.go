@@ -0,0 +1,64 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FileCheckpoint keeps each worker ID's high-water mark in "<Dir>/worker-<id>.hwm". Saves write a
+// temporary file, sync it and rename it over the old one, so a crash leaves either mark intact.
+type FileCheckpoint struct {
+	Dir string
+}
+
+func NewFileCheckpoint(dir string) *FileCheckpoint {
+	return &FileCheckpoint{Dir: dir}
+}
+
+func (c *FileCheckpoint) LoadHighWater(_ context.Context, workerId int64) (int64, error) {
+	data, err := os.ReadFile(c.path(workerId))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("file checkpoint: %w", err)
+	}
+	mark, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("file checkpoint: %s: %w", c.path(workerId), err)
+	}
+	return mark, nil
+}
+
+func (c *FileCheckpoint) SaveHighWater(_ context.Context, workerId, mark int64) error {
+	if err := os.MkdirAll(c.Dir, 0o777); err != nil {
+		return fmt.Errorf("file checkpoint: %w", err)
+	}
+	f, err := os.CreateTemp(c.Dir, "worker-*.tmp")
+	if err != nil {
+		return fmt.Errorf("file checkpoint: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.WriteString(strconv.FormatInt(mark, 10)); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), c.path(workerId))
+	}
+	if err != nil {
+		return fmt.Errorf("file checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (c *FileCheckpoint) path(workerId int64) string {
+	return filepath.Join(c.Dir, "worker-"+strconv.FormatInt(workerId, 10)+".hwm")
+}
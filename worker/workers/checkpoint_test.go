@@ -0,0 +1,50 @@
+package workers
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpoint_HighWater(t *testing.T) {
+	ctx, dir := context.Background(), t.TempDir()
+	local := NewLocalAssigner(filepath.Join(dir, "slots"), 3)
+	if _, err := local.NextWorkerIdContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, store := range map[string]interface {
+		LoadHighWater(ctx context.Context, workerId int64) (int64, error)
+		SaveHighWater(ctx context.Context, workerId, mark int64) error
+	}{
+		"file":  NewFileCheckpoint(filepath.Join(dir, "hwm")),
+		"local": local,
+	} {
+		if mark, err := store.LoadHighWater(ctx, 0); err != nil || mark != 0 {
+			t.Errorf("%s: fresh mark = %d, %v, want 0", name, mark, err)
+		}
+		for _, want := range []int64{1_700_000_000_000, 1_700_000_005_000} {
+			if err := store.SaveHighWater(ctx, 0, want); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if mark, err := store.LoadHighWater(ctx, 0); err != nil || mark != want {
+				t.Errorf("%s: mark = %d, %v, want %d", name, mark, err, want)
+			}
+		}
+	}
+
+	// The slot keeps the mark across owners
+	if err := local.ReleaseWorkerId(ctx, 0, 42); err != nil {
+		t.Fatal(err)
+	}
+	next := NewLocalAssigner(filepath.Join(dir, "slots"), 3)
+	if _, err := next.NextWorkerIdContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mark, err := next.LoadHighWater(ctx, 0); err != nil || mark != 1_700_000_005_000 {
+		t.Errorf("mark after restart = %d, %v", mark, err)
+	}
+	if fence := next.FenceTimestamp(); fence != 42 {
+		t.Errorf("fence after restart = %d, want 42", fence)
+	}
+}
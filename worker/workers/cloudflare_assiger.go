@@ -1,18 +1,222 @@
 package workers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gomsr/atom-cloudflare/kvs"
 	"github.com/gomsr/atom-cloudflare/kvs/worker"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
-type CloudflareAssigner struct{}
+const (
+	DefaultCloudflareRetries    = 10
+	DefaultCloudflareBackoff    = 100 * time.Millisecond
+	DefaultCloudflareMaxBackoff = 5 * time.Second
+	DefaultCloudflareTimeout    = 30 * time.Second
+	DefaultCloudflareBaseURL    = "https://api.cloudflare.com/client/v4"
+)
+
+// KVClient reads and writes the worker ID counter, stored as atom-cloudflare's {"value":"N"}
+// envelope. Get returns "" for a missing key.
+type KVClient interface {
+	Get(ctx context.Context, key string) (string, error)
+	Put(ctx context.Context, key, value string) error
+}
+
+// CloudflareAssigner increments a counter in Workers KV. KV has no compare-and-swap, so two nodes
+// starting at the same moment can read the same counter; keep starts staggered or use a lease store.
+type CloudflareAssigner struct {
+	KV         KVClient      // defaults to NewHTTPKVClient, configured from the atom-cloudflare env
+	Key        string        // defaults to worker.KeyUidsWorker
+	Retries    int           // attempts, defaults to DefaultCloudflareRetries
+	Backoff    time.Duration // delay after the first failure, doubled after each, defaults to DefaultCloudflareBackoff
+	MaxBackoff time.Duration // defaults to DefaultCloudflareMaxBackoff
+	Timeout    time.Duration // overall deadline of all attempts, defaults to DefaultCloudflareTimeout
+
+	Logf func(format string, args ...any) // logs failed attempts, defaults to fmt.Printf
+}
+
+func NewCloudflareAssigner(kv KVClient) *CloudflareAssigner {
+	return &CloudflareAssigner{KV: kv}
+}
+
+func (c *CloudflareAssigner) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	kv, key := c.KV, c.Key
+	if kv == nil {
+		kv = NewHTTPKVClient()
+	}
+	if key == "" {
+		key = worker.KeyUidsWorker
+	}
+	retries, backoff, maxBackoff, timeout := c.Retries, c.Backoff, c.MaxBackoff, c.Timeout
+	if retries <= 0 {
+		retries = DefaultCloudflareRetries
+	}
+	if backoff <= 0 {
+		backoff = DefaultCloudflareBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultCloudflareMaxBackoff
+	}
+	if timeout <= 0 {
+		timeout = DefaultCloudflareTimeout
+	}
+	logf := c.Logf
+	if logf == nil {
+		logf = func(format string, args ...any) { fmt.Printf(format+"\n", args...) }
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		var id int64
+		if id, err = next(ctx, kv, key); err == nil {
+			return id, nil
+		}
+		if attempt == retries || ctx.Err() != nil {
+			break
+		}
+		logf("cloudflare assigner: attempt %d/%d failed: %v, retrying in %s", attempt, retries, err, backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil && !errors.Is(err, ctxErr) {
+		err = fmt.Errorf("%w, last error: %v", ctxErr, err)
+	}
+	return 0, fmt.Errorf("cloudflare assigner: could not assign worker id: %w", err)
+}
+
+func next(ctx context.Context, kv KVClient, key string) (int64, error) {
+	value, err := kv.Get(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	current, err := parseCounter(value)
+	if err != nil {
+		return 0, fmt.Errorf("counter %s=%q is not an integer", key, value)
+	}
+	if err = kv.Put(ctx, key, formatCounter(current+1)); err != nil {
+		return 0, err
+	}
+	return current + 1, nil
+}
+
+// counterEnvelope is how atom-cloudflare stores the counter, e.g. {"value":"42"}
+type counterEnvelope struct {
+	Value any `json:"value"`
+}
 
-func (c *CloudflareAssigner) NextWorkerId() int64 {
-	for i := 0; i < 10; i++ {
-		if id, err := worker.NextWorkerID(); err != nil {
-			continue
-		} else {
-			return id
+// parseCounter reads the atom-cloudflare envelope, or a bare integer. Empty is 0.
+func parseCounter(value string) (int64, error) {
+	if value = strings.TrimSpace(value); strings.HasPrefix(value, "{") {
+		var envelope counterEnvelope
+		dec := json.NewDecoder(strings.NewReader(value))
+		dec.UseNumber()
+		if err := dec.Decode(&envelope); err != nil {
+			return 0, err
+		}
+		if envelope.Value == nil {
+			return 0, nil
 		}
+		value = strings.TrimSpace(fmt.Sprint(envelope.Value))
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// formatCounter writes the atom-cloudflare envelope, so nodes on older versions keep reading it
+func formatCounter(current int64) string {
+	data, _ := json.Marshal(counterEnvelope{Value: strconv.FormatInt(current, 10)})
+	return string(data)
+}
+
+// HTTPKVClient talks to the Workers KV REST API directly, or to any server mimicking it.
+type HTTPKVClient struct {
+	BaseURL   string // defaults to DefaultCloudflareBaseURL
+	AccountID string
+	Namespace string
+	Token     string
+	Client    *http.Client // defaults to http.DefaultClient
+}
+
+// NewHTTPKVClient uses the account, namespace and token atom-cloudflare reads from its env.
+func NewHTTPKVClient() *HTTPKVClient {
+	return &HTTPKVClient{AccountID: kvs.AccountID, Namespace: worker.Namespace, Token: kvs.Token}
+}
+
+func (c *HTTPKVClient) Get(ctx context.Context, key string) (string, error) {
+	body, status, err := c.do(ctx, http.MethodGet, key, "")
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusNotFound {
+		return "", nil
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("kv get %s: %d %s", key, status, strings.TrimSpace(body))
+	}
+	return body, nil
+}
+
+func (c *HTTPKVClient) Put(ctx context.Context, key, value string) error {
+	body, status, err := c.do(ctx, http.MethodPut, key, value)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("kv put %s: %d %s", key, status, strings.TrimSpace(body))
+	}
+	return nil
+}
+
+func (c *HTTPKVClient) do(ctx context.Context, method, key, value string) (string, int, error) {
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultCloudflareBaseURL
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	u := strings.TrimRight(base, "/") + "/accounts/" + url.PathEscape(c.AccountID) +
+		"/storage/kv/namespaces/" + url.PathEscape(c.Namespace) + "/values/" + url.PathEscape(key)
+	req, err := http.NewRequestWithContext(ctx, method, u, strings.NewReader(value))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "text/plain")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", 0, err
 	}
-	panic("Could not assign worker id")
+	return string(body), resp.StatusCode, nil
 }
@@ -0,0 +1,115 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// kvServer stands in for the Workers KV REST API; the first failures requests answer 500.
+type kvServer struct {
+	mu       sync.Mutex
+	values   map[string]string
+	failures int
+	requests int
+}
+
+func (s *kvServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if r.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	key := r.URL.Path[len("/accounts/acc/storage/kv/namespaces/ns/values/"):]
+	switch r.Method {
+	case http.MethodGet:
+		v, ok := s.values[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, v)
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		s.values[key] = string(body)
+	}
+}
+
+func newKVServer(t *testing.T, failures int) (*kvServer, *HTTPKVClient) {
+	s := &kvServer{values: map[string]string{}, failures: failures}
+	server := httptest.NewServer(s)
+	t.Cleanup(server.Close)
+	return s, &HTTPKVClient{BaseURL: server.URL, AccountID: "acc", Namespace: "ns", Token: "token", Client: server.Client()}
+}
+
+func TestCloudflareAssigner_NextWorkerIdContext(t *testing.T) {
+	s, kv := newKVServer(t, 0)
+	assigner := NewCloudflareAssigner(kv)
+
+	// counters written by atom-cloudflare, and bare ones
+	for _, seed := range []string{`{"value":"41"}`, `{"value":41,"metadata":""}`, "41"} {
+		s.values["uids-worker"] = seed
+		for want := int64(42); want <= 43; want++ {
+			id, err := assigner.NextWorkerIdContext(context.Background())
+			if err != nil || id != want {
+				t.Fatalf("NextWorkerIdContext() after %s = %d, %v, want %d", seed, id, err, want)
+			}
+		}
+		if s.values["uids-worker"] != `{"value":"43"}` {
+			t.Errorf("counter = %s, want the atom-cloudflare envelope", s.values["uids-worker"])
+		}
+	}
+
+	_, kv = newKVServer(t, 0)
+	if id, err := NewCloudflareAssigner(kv).NextWorkerIdContext(context.Background()); err != nil || id != 1 {
+		t.Fatalf("NextWorkerIdContext() on a missing key = %d, %v, want 1", id, err)
+	}
+}
+
+func TestCloudflareAssigner_Retries(t *testing.T) {
+	s, kv := newKVServer(t, 2)
+	var logged int
+	assigner := &CloudflareAssigner{KV: kv, Retries: 3, Backoff: time.Millisecond, Logf: func(string, ...any) { logged++ }}
+	if id, err := assigner.NextWorkerIdContext(context.Background()); err != nil || id != 1 {
+		t.Fatalf("NextWorkerIdContext() = %d, %v, want 1", id, err)
+	}
+	if logged != 2 {
+		t.Errorf("logged %d failed attempts, want 2", logged)
+	}
+
+	s.failures = 3
+	if _, err := assigner.NextWorkerIdContext(context.Background()); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+	if s.requests != 7 {
+		t.Errorf("server saw %d requests, want 7", s.requests)
+	}
+}
+
+func TestCloudflareAssigner_Timeout(t *testing.T) {
+	_, kv := newKVServer(t, 1_000)
+	assigner := &CloudflareAssigner{KV: kv, Retries: 1_000, Backoff: 10 * time.Millisecond, Timeout: 50 * time.Millisecond, Logf: func(string, ...any) {}}
+
+	start := time.Now()
+	_, err := assigner.NextWorkerIdContext(context.Background())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("NextWorkerIdContext() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("gave up after %s, want about 50ms", elapsed)
+	}
+}
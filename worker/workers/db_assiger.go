@@ -1,7 +1,158 @@
 package workers
 
-type DbAssigner struct{}
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
 
-func (c *DbAssigner) NextWorkerId() int64 {
-	panic("Could not assign worker id")
+// WorkerNodeTable is the table used by uid-generator's DisposableWorkerIdAssigner.
+const WorkerNodeTable = "WORKER_NODE"
+
+// WorkerNodeType mirrors uid-generator's WorkerNodeType.
+type WorkerNodeType int
+
+const (
+	ContainerNode WorkerNodeType = 1
+	ActualNode    WorkerNodeType = 2
+)
+
+// Dialect selects the SQL flavour used to create and insert into WORKER_NODE.
+type Dialect string
+
+const (
+	MySQL    Dialect = "mysql"
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// DbAssigner assigns worker IDs by inserting a row into WORKER_NODE and using the auto-increment key.
+// Every call consumes a new ID, the same use-once policy as uid-generator.
+type DbAssigner struct {
+	DB       *sql.DB
+	Dialect  Dialect
+	Table    string         // defaults to WorkerNodeTable
+	HostName string         // defaults to $JPAAS_HOST or os.Hostname()
+	Port     string         // defaults to $JPAAS_HTTP_PORT or "<millis>-<random>"
+	NodeType WorkerNodeType // defaults to ContainerNode when $JPAAS_HOST is set, otherwise ActualNode
+}
+
+func NewDbAssigner(db *sql.DB, dialect Dialect) *DbAssigner {
+	return &DbAssigner{DB: db, Dialect: dialect}
+}
+
+// NextWorkerIdContext creates the table if missing, registers this node and returns the new row ID.
+func (c *DbAssigner) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	if c.DB == nil {
+		return 0, errors.New("db assigner: sql.DB is nil, bind one with worker.Replace(worker.DbWorkerId, ...)")
+	}
+
+	table := c.Table
+	if table == "" {
+		table = WorkerNodeTable
+	}
+	createSQL, insertSQL, err := c.Dialect.statements(table)
+	if err != nil {
+		return 0, err
+	}
+	if _, err = c.DB.ExecContext(ctx, createSQL); err != nil {
+		return 0, fmt.Errorf("db assigner: create table %s: %w", table, err)
+	}
+
+	host, port, nodeType := c.node()
+	now := time.Now()
+	launchDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	args := []any{host, port, int(nodeType), launchDate, now, now}
+
+	var id int64
+	if c.Dialect == Postgres {
+		err = c.DB.QueryRowContext(ctx, insertSQL, args...).Scan(&id)
+	} else {
+		var res sql.Result
+		if res, err = c.DB.ExecContext(ctx, insertSQL, args...); err == nil {
+			id, err = res.LastInsertId()
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("db assigner: insert into %s: %w", table, err)
+	}
+
+	fmt.Printf("Add worker node to %s: host: %s, port: %s, type: %d, id: %d\n", table, host, port, nodeType, id)
+	return id, nil
+}
+
+// node resolves host, port and type the same way uid-generator's WorkerNodeEntity is built.
+func (c *DbAssigner) node() (string, string, WorkerNodeType) {
+	host, port, nodeType := c.HostName, c.Port, c.NodeType
+	envHost, envPort := os.Getenv("JPAAS_HOST"), os.Getenv("JPAAS_HTTP_PORT")
+	if nodeType == 0 {
+		if envHost != "" && envPort != "" {
+			nodeType = ContainerNode
+		} else {
+			nodeType = ActualNode
+		}
+	}
+	if host == "" {
+		if nodeType == ContainerNode && envHost != "" {
+			host = envHost
+		} else if name, err := os.Hostname(); err == nil {
+			host = name
+		} else {
+			host = "unknown"
+		}
+	}
+	if port == "" {
+		if nodeType == ContainerNode && envPort != "" {
+			port = envPort
+		} else {
+			port = fmt.Sprintf("%d-%d", time.Now().UnixMilli(), rand.Intn(100000))
+		}
+	}
+	return host, port, nodeType
+}
+
+func (d Dialect) statements(table string) (string, string, error) {
+	const columns = "HOST_NAME, PORT, TYPE, LAUNCH_DATE, MODIFIED, CREATED"
+	switch d {
+	case MySQL:
+		return "CREATE TABLE IF NOT EXISTS " + table + ` (
+  ID BIGINT NOT NULL AUTO_INCREMENT COMMENT 'auto increment id',
+  HOST_NAME VARCHAR(64) NOT NULL COMMENT 'host name',
+  PORT VARCHAR(64) NOT NULL COMMENT 'port',
+  TYPE INT NOT NULL COMMENT 'node type: ACTUAL or CONTAINER',
+  LAUNCH_DATE DATE NOT NULL COMMENT 'launch date',
+  MODIFIED TIMESTAMP NOT NULL COMMENT 'modified time',
+  CREATED TIMESTAMP NOT NULL COMMENT 'created time',
+  PRIMARY KEY(ID)
+) COMMENT='DB WorkerID Assigner for UID Generator', ENGINE = INNODB`,
+			"INSERT INTO " + table + " (" + columns + ") VALUES (?, ?, ?, ?, ?, ?)", nil
+	case Postgres:
+		return "CREATE TABLE IF NOT EXISTS " + table + ` (
+  ID BIGSERIAL PRIMARY KEY,
+  HOST_NAME VARCHAR(64) NOT NULL,
+  PORT VARCHAR(64) NOT NULL,
+  TYPE INT NOT NULL,
+  LAUNCH_DATE DATE NOT NULL,
+  MODIFIED TIMESTAMP NOT NULL,
+  CREATED TIMESTAMP NOT NULL
+)`,
+			"INSERT INTO " + table + " (" + columns + ") VALUES ($1, $2, $3, $4, $5, $6) RETURNING ID", nil
+	case SQLite:
+		return "CREATE TABLE IF NOT EXISTS " + table + ` (
+  ID INTEGER PRIMARY KEY AUTOINCREMENT,
+  HOST_NAME VARCHAR(64) NOT NULL,
+  PORT VARCHAR(64) NOT NULL,
+  TYPE INT NOT NULL,
+  LAUNCH_DATE DATE NOT NULL,
+  MODIFIED TIMESTAMP NOT NULL,
+  CREATED TIMESTAMP NOT NULL
+)`,
+			"INSERT INTO " + table + " (" + columns + ") VALUES (?, ?, ?, ?, ?, ?)", nil
+	default:
+		return "", "", fmt.Errorf("db assigner: unsupported dialect %q", string(d))
+	}
 }
@@ -0,0 +1,136 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDriver is an in-process database/sql driver that keeps WORKER_NODE rows in memory.
+type fakeDriver struct {
+	mu     sync.Mutex
+	tables map[string]bool
+	rows   [][]driver.Value
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	id, err := s.run(args)
+	return fakeResult(id), err
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	id, err := s.run(args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{id: id}, nil
+}
+
+func (s *fakeStmt) run(args []driver.Value) (int64, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	fields := strings.Fields(s.query)
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE IF NOT EXISTS"):
+		s.d.tables[fields[5]] = true
+		return 0, nil
+	case strings.HasPrefix(s.query, "INSERT INTO"):
+		if !s.d.tables[fields[2]] {
+			return 0, errors.New("no such table: " + fields[2])
+		}
+		if len(args) != 6 {
+			return 0, errors.New("expected 6 args")
+		}
+		s.d.rows = append(s.d.rows, args)
+		return int64(len(s.d.rows)), nil
+	}
+	return 0, errors.New("unexpected query: " + s.query)
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (r fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+type fakeRows struct {
+	id   int64
+	done bool
+}
+
+func (r *fakeRows) Columns() []string { return []string{"ID"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.id
+	return nil
+}
+
+// Connect and Driver make fakeDriver its own driver.Connector, so tests open it without sql.Register
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return d.Open("") }
+func (d *fakeDriver) Driver() driver.Driver                        { return d }
+
+func openFakeDB(t *testing.T) (*sql.DB, *fakeDriver) {
+	d := &fakeDriver{tables: map[string]bool{}}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, d
+}
+
+func TestDbAssigner_NextWorkerIdContext(t *testing.T) {
+	for _, dialect := range []Dialect{MySQL, Postgres, SQLite} {
+		t.Run(string(dialect), func(t *testing.T) {
+			db, d := openFakeDB(t)
+			assigner := NewDbAssigner(db, dialect)
+			assigner.HostName, assigner.Port = "host-1", "8080"
+
+			for want := int64(1); want <= 3; want++ {
+				id, err := assigner.NextWorkerIdContext(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if id != want {
+					t.Errorf("NextWorkerIdContext() = %d, want %d", id, want)
+				}
+			}
+			if row := d.rows[0]; row[0] != "host-1" || row[1] != "8080" || row[2] != int64(ActualNode) {
+				t.Errorf("unexpected row %v", row)
+			}
+		})
+	}
+}
+
+func TestDbAssigner_Errors(t *testing.T) {
+	if _, err := (&DbAssigner{}).NextWorkerIdContext(context.Background()); err == nil {
+		t.Error("expected error for nil db")
+	}
+
+	db, _ := openFakeDB(t)
+	if _, err := NewDbAssigner(db, "oracle").NextWorkerIdContext(context.Background()); err == nil {
+		t.Error("expected error for unsupported dialect")
+	}
+}
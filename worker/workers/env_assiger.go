@@ -0,0 +1,104 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+const (
+	DefaultWorkerIdEnv       = "WORKER_ID"
+	DefaultWorkerIdOffsetEnv = "WORKER_ID_OFFSET"
+)
+
+// DefaultOrdinalPattern matches the ordinal suffix of a StatefulSet pod name, e.g. "svc-3".
+var DefaultOrdinalPattern = regexp.MustCompile(`-(\d+)$`)
+
+// EnvAssigner derives the worker ID from an environment variable or, when it is unset, from the
+// ordinal in the hostname of a Kubernetes StatefulSet pod. The offset separates clusters that
+// share a worker ID space.
+type EnvAssigner struct {
+	EnvKey         string         // defaults to DefaultWorkerIdEnv
+	OffsetEnvKey   string         // read when Offset is 0, defaults to DefaultWorkerIdOffsetEnv
+	Offset         int64          // added to the derived ID
+	Hostname       string         // defaults to $HOSTNAME, then os.Hostname()
+	OrdinalPattern *regexp.Regexp // first submatch is the ordinal, defaults to DefaultOrdinalPattern
+
+	LookupEnv func(key string) (string, bool) // defaults to os.LookupEnv
+}
+
+func (c *EnvAssigner) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	lookup := c.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	envKey, offsetKey := c.EnvKey, c.OffsetEnvKey
+	if envKey == "" {
+		envKey = DefaultWorkerIdEnv
+	}
+	if offsetKey == "" {
+		offsetKey = DefaultWorkerIdOffsetEnv
+	}
+
+	offset := c.Offset
+	if v, ok := lookup(offsetKey); ok && offset == 0 && v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("env assigner: $%s=%q is not an integer", offsetKey, v)
+		}
+		offset = parsed
+	}
+
+	var id int64
+	if v, ok := lookup(envKey); ok && v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("env assigner: $%s=%q is not an integer", envKey, v)
+		}
+		id = parsed
+	} else {
+		ordinal, err := c.ordinal(lookup)
+		if err != nil {
+			return 0, fmt.Errorf("env assigner: $%s is unset and %w", envKey, err)
+		}
+		id = ordinal
+	}
+
+	if id+offset < 0 {
+		return 0, fmt.Errorf("env assigner: worker id %d with offset %d is negative", id, offset)
+	}
+	return id + offset, nil
+}
+
+func (c *EnvAssigner) ordinal(lookup func(string) (string, bool)) (int64, error) {
+	host := c.Hostname
+	if host == "" {
+		host, _ = lookup("HOSTNAME")
+	}
+	if host == "" {
+		var err error
+		if host, err = os.Hostname(); err != nil {
+			return 0, fmt.Errorf("hostname is unknown: %w", err)
+		}
+	}
+
+	pattern := c.OrdinalPattern
+	if pattern == nil {
+		pattern = DefaultOrdinalPattern
+	}
+	m := pattern.FindStringSubmatch(host)
+	if len(m) < 2 {
+		return 0, fmt.Errorf("hostname %q has no ordinal matching %s", host, pattern)
+	}
+	ordinal, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("hostname %q ordinal %q is not an integer", host, m[1])
+	}
+	return ordinal, nil
+}
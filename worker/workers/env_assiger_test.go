@@ -0,0 +1,41 @@
+package workers
+
+import (
+	"context"
+	"regexp"
+	"testing"
+)
+
+func TestEnvAssigner_NextWorkerIdContext(t *testing.T) {
+	tests := []struct {
+		name     string
+		assigner EnvAssigner
+		env      map[string]string
+		want     int64
+		wantErr  bool
+	}{
+		{name: "env", env: map[string]string{"WORKER_ID": "12"}, want: 12},
+		{name: "env with offset", env: map[string]string{"WORKER_ID": "12", "WORKER_ID_OFFSET": "100"}, want: 112},
+		{name: "custom env key", assigner: EnvAssigner{EnvKey: "POD_INDEX"}, env: map[string]string{"POD_INDEX": "4"}, want: 4},
+		{name: "hostname ordinal", env: map[string]string{"HOSTNAME": "svc-3"}, want: 3},
+		{name: "ordinal with offset", assigner: EnvAssigner{Offset: 32}, env: map[string]string{"HOSTNAME": "svc-3"}, want: 35},
+		{name: "custom pattern", assigner: EnvAssigner{Hostname: "node07.dc1", OrdinalPattern: regexp.MustCompile(`^node(\d+)\.`)}, want: 7},
+		{name: "invalid env", env: map[string]string{"WORKER_ID": "abc"}, wantErr: true},
+		{name: "invalid offset", env: map[string]string{"WORKER_ID": "1", "WORKER_ID_OFFSET": "x"}, wantErr: true},
+		{name: "no ordinal", env: map[string]string{"HOSTNAME": "web"}, wantErr: true},
+		{name: "negative", assigner: EnvAssigner{Offset: -5}, env: map[string]string{"WORKER_ID": "1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assigner := tt.assigner
+			assigner.LookupEnv = func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}
+			got, err := assigner.NextWorkerIdContext(context.Background())
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("NextWorkerIdContext() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
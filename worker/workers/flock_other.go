@@ -0,0 +1,16 @@
+//go:build !unix
+
+package workers
+
+import (
+	"errors"
+	"os"
+)
+
+var errFlockUnsupported = errors.New("file locking is not supported on this platform")
+
+func lockFile(*os.File) error { return errFlockUnsupported }
+
+func tryLockFile(*os.File) (bool, error) { return false, errFlockUnsupported }
+
+func unlockFile(*os.File) error { return errFlockUnsupported }
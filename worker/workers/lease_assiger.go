@@ -0,0 +1,204 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultLeaseTTL = 30 * time.Second
+)
+
+// LeaseAssigner holds a worker ID under a TTL lease and renews it from a background heartbeat.
+// An ID is handed to another node only after its lease expired or was released, so IDs are
+// reused across restarts instead of being thrown away.
+type LeaseAssigner struct {
+	Store       LeaseStore
+	MaxWorkerId int64
+	TTL         time.Duration // defaults to DefaultLeaseTTL
+	Heartbeat   time.Duration // defaults to TTL / 3
+	Owner       string        // defaults to "<hostname>-<pid>-<nanos>"
+
+	// OnLost is called once from the heartbeat goroutine when the lease can no longer be renewed.
+	OnLost func(workerId int64, err error)
+
+	mu       sync.Mutex
+	workerId int64
+	fence    int64
+	held     bool
+	stop     chan struct{}
+	done     chan struct{}
+
+	lostMu sync.Mutex // separate from mu, which is held while waiting for the heartbeat to exit
+	lost   error
+}
+
+func NewLeaseAssigner(store LeaseStore, maxWorkerId int64, ttl time.Duration) *LeaseAssigner {
+	return &LeaseAssigner{Store: store, MaxWorkerId: maxWorkerId, TTL: ttl}
+}
+
+// SetMaxWorkerId lowers MaxWorkerId to the generator's budget.
+func (c *LeaseAssigner) SetMaxWorkerId(maxWorkerId int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.MaxWorkerId <= 0 || c.MaxWorkerId > maxWorkerId {
+		c.MaxWorkerId = maxWorkerId
+	}
+}
+
+// NextWorkerIdContext acquires a lease and starts the heartbeat. Calling it again while the
+// lease is held returns the same ID.
+func (c *LeaseAssigner) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Store == nil {
+		return 0, errors.New("lease assigner: store is nil")
+	}
+	if c.held && c.Err() == nil {
+		return c.workerId, nil
+	}
+	c.stopHeartbeat()
+
+	if c.TTL <= 0 {
+		c.TTL = DefaultLeaseTTL
+	}
+	if c.Heartbeat <= 0 {
+		c.Heartbeat = c.TTL / 3
+	}
+	if c.Owner == "" {
+		host, _ := os.Hostname()
+		c.Owner = fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano())
+	}
+
+	lease, err := c.Store.Acquire(ctx, c.Owner, c.MaxWorkerId, c.TTL)
+	if err != nil {
+		return 0, fmt.Errorf("lease assigner: %w", err)
+	}
+
+	c.workerId, c.fence, c.held = lease.WorkerId, lease.LastTimestamp, true
+	c.setLost(nil)
+	c.stop, c.done = make(chan struct{}), make(chan struct{})
+	go c.heartbeat(lease.WorkerId, c.stop, c.done)
+	return lease.WorkerId, nil
+}
+
+// FenceTimestamp returns the last timestamp the previous owner of the ID released it with.
+func (c *LeaseAssigner) FenceTimestamp() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fence
+}
+
+// ReleaseWorkerId stops the heartbeat and releases the lease, recording lastTimestamp for the next owner.
+func (c *LeaseAssigner) ReleaseWorkerId(ctx context.Context, workerId, lastTimestamp int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.held || workerId != c.workerId {
+		return nil
+	}
+	c.stopHeartbeat()
+	c.held = false
+	return c.Store.Release(ctx, c.workerId, c.Owner, lastTimestamp)
+}
+
+// VerifyWorkerId renews the lease now and fails if another owner holds it. Transient store errors
+// are left to the heartbeat, which gives up once the TTL has passed without a renewal.
+func (c *LeaseAssigner) VerifyWorkerId(ctx context.Context, workerId int64) error {
+	c.mu.Lock()
+	held, owner, ttl := c.held && workerId == c.workerId, c.Owner, c.TTL
+	c.mu.Unlock()
+
+	if !held {
+		return fmt.Errorf("lease assigner: worker id %d: %w", workerId, ErrLeaseLost)
+	}
+	if err := c.Err(); err != nil {
+		return err
+	}
+	if err := c.Store.Renew(ctx, workerId, owner, ttl); errors.Is(err, ErrLeaseLost) {
+		lost := fmt.Errorf("lease assigner: worker id %d: %w", workerId, err)
+		c.setLost(lost)
+		return lost
+	}
+	return nil
+}
+
+// Err returns a non-nil error once the lease has been lost.
+func (c *LeaseAssigner) Err() error {
+	c.lostMu.Lock()
+	defer c.lostMu.Unlock()
+	return c.lost
+}
+
+func (c *LeaseAssigner) setLost(err error) {
+	c.lostMu.Lock()
+	defer c.lostMu.Unlock()
+	c.lost = err
+}
+
+// Close stops the heartbeat and releases the lease without a timestamp.
+func (c *LeaseAssigner) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stopHeartbeat()
+	if !c.held {
+		return nil
+	}
+	c.held = false
+	return c.Store.Release(context.Background(), c.workerId, c.Owner, 0)
+}
+
+func (c *LeaseAssigner) stopHeartbeat() {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+		c.stop, c.done = nil, nil
+	}
+}
+
+func (c *LeaseAssigner) heartbeat(workerId int64, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(c.Heartbeat)
+	defer ticker.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.Heartbeat)
+		err := c.Store.Renew(ctx, workerId, c.Owner, c.TTL)
+		cancel()
+		if err == nil {
+			renewed = time.Now()
+			continue
+		}
+
+		// transient store errors are retried until the lease would have expired anyway
+		if !errors.Is(err, ErrLeaseLost) && time.Since(renewed) < c.TTL {
+			fmt.Printf("Renew worker id %d lease failed: %v\n", workerId, err)
+			continue
+		}
+
+		if !errors.Is(err, ErrLeaseLost) {
+			err = fmt.Errorf("%w: %w", ErrLeaseLost, err)
+		}
+		lost := fmt.Errorf("lease assigner: worker id %d: %w", workerId, err)
+		c.setLost(lost)
+		if c.OnLost != nil {
+			c.OnLost(workerId, lost)
+		}
+		return
+	}
+}
@@ -0,0 +1,128 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type manualNow struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (m *manualNow) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *manualNow) Add(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = m.now.Add(d)
+}
+
+func TestLeaseStores(t *testing.T) {
+	stores := map[string]func(now func() time.Time) LeaseStore{
+		"memory": func(now func() time.Time) LeaseStore {
+			s := NewMemoryLeaseStore()
+			s.Now = now
+			return s
+		},
+		"file": func(now func() time.Time) LeaseStore {
+			s := NewFileLeaseStore(filepath.Join(t.TempDir(), "leases.json"))
+			s.Now = now
+			return s
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			ctx, clock := context.Background(), &manualNow{now: time.Unix(1_700_000_000, 0)}
+			store := newStore(clock.Now)
+
+			a, _ := store.Acquire(ctx, "a", 1, time.Minute)
+			b, _ := store.Acquire(ctx, "b", 1, time.Minute)
+			if a.WorkerId != 0 || b.WorkerId != 1 {
+				t.Fatalf("Acquire() = %d, %d, want 0, 1", a.WorkerId, b.WorkerId)
+			}
+			if _, err := store.Acquire(ctx, "c", 1, time.Minute); !errors.Is(err, ErrNoFreeWorkerId) {
+				t.Fatalf("Acquire() error = %v, want ErrNoFreeWorkerId", err)
+			}
+
+			clock.Add(40 * time.Second)
+			if err := store.Renew(ctx, a.WorkerId, "a", time.Minute); err != nil {
+				t.Fatal(err)
+			}
+			clock.Add(40 * time.Second)
+			if c, err := store.Acquire(ctx, "c", 1, time.Minute); err != nil || c.WorkerId != b.WorkerId {
+				t.Fatalf("Acquire() = %d, %v, want expired id %d", c.WorkerId, err, b.WorkerId)
+			}
+			if err := store.Renew(ctx, b.WorkerId, "b", time.Minute); !errors.Is(err, ErrLeaseLost) {
+				t.Fatalf("Renew() error = %v, want ErrLeaseLost", err)
+			}
+
+			if err := store.Release(ctx, a.WorkerId, "a", 1_700_000_123); err != nil {
+				t.Fatal(err)
+			}
+			d, _ := store.Acquire(ctx, "d", 1, time.Minute)
+			if d.WorkerId != a.WorkerId || d.LastTimestamp != 1_700_000_123 {
+				t.Fatalf("Acquire() = %+v, want released id %d fenced at 1700000123", d, a.WorkerId)
+			}
+		})
+	}
+}
+
+func TestLeaseAssigner_Heartbeat(t *testing.T) {
+	store := NewMemoryLeaseStore()
+	assigner := NewLeaseAssigner(store, 3, 60*time.Millisecond)
+	assigner.Heartbeat = 10 * time.Millisecond
+	id, err := assigner.NextWorkerIdContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if other, _ := store.Acquire(context.Background(), "other", 3, time.Minute); other.WorkerId == id {
+		t.Fatalf("heartbeat did not keep worker id %d", id)
+	}
+	if err = assigner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = assigner.ReleaseWorkerId(context.Background(), id, 1_700_000_000); err != nil {
+		t.Fatal(err)
+	}
+	next := NewLeaseAssigner(store, 0, time.Minute)
+	defer next.Close()
+	if other, _ := next.NextWorkerIdContext(context.Background()); other != id || next.FenceTimestamp() != 1_700_000_000 {
+		t.Fatalf("NextWorkerIdContext() = %d fenced at %d, want released id %d fenced at 1700000000", other, next.FenceTimestamp(), id)
+	}
+}
+
+func TestLeaseAssigner_Lost(t *testing.T) {
+	store := NewMemoryLeaseStore()
+	lost := make(chan error, 1)
+	assigner := NewLeaseAssigner(store, 3, time.Minute)
+	assigner.Heartbeat = 10 * time.Millisecond
+	assigner.OnLost = func(_ int64, err error) { lost <- err }
+	defer assigner.Close()
+
+	id, err := assigner.NextWorkerIdContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = store.Release(context.Background(), id, assigner.Owner, 0)
+
+	select {
+	case err = <-lost:
+		if !errors.Is(err, ErrLeaseLost) || !errors.Is(assigner.Err(), ErrLeaseLost) {
+			t.Fatalf("lost error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lease loss was not detected")
+	}
+}
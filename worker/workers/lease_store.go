@@ -0,0 +1,184 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrLeaseLost       = errors.New("worker id lease lost")
+	ErrNoFreeWorkerId  = errors.New("no free worker id")
+	errInvalidLeaseTTL = errors.New("lease ttl must be positive")
+)
+
+// Lease is a worker ID held by Owner until Expires. LastTimestamp is the highest timestamp a
+// previous owner reported on release; the next owner must issue above it.
+type Lease struct {
+	WorkerId      int64     `json:"worker_id"`
+	Owner         string    `json:"owner"`
+	Expires       time.Time `json:"expires"`
+	LastTimestamp int64     `json:"last_timestamp,omitempty"`
+}
+
+// LeaseStore persists worker ID leases. Acquire must be atomic for every node sharing the store.
+type LeaseStore interface {
+	// Acquire claims the lowest worker ID in [0, maxWorkerId] without a live lease.
+	Acquire(ctx context.Context, owner string, maxWorkerId int64, ttl time.Duration) (Lease, error)
+
+	// Renew extends the lease, or returns ErrLeaseLost if owner no longer holds workerId.
+	Renew(ctx context.Context, workerId int64, owner string, ttl time.Duration) error
+
+	// Release drops the lease so the ID can be handed out immediately, recording lastTimestamp
+	// (if higher than the recorded one) for the next owner.
+	Release(ctx context.Context, workerId int64, owner string, lastTimestamp int64) error
+}
+
+// leaseTable holds the leases of one store, keyed by worker ID.
+type leaseTable map[int64]Lease
+
+func (t leaseTable) acquire(owner string, maxWorkerId int64, ttl time.Duration, now time.Time) (Lease, error) {
+	if ttl <= 0 {
+		return Lease{}, errInvalidLeaseTTL
+	}
+	for id := int64(0); id <= maxWorkerId; id++ {
+		l, ok := t[id]
+		if ok && l.Owner != "" && l.Owner != owner && l.Expires.After(now) {
+			continue
+		}
+		t[id] = Lease{WorkerId: id, Owner: owner, Expires: now.Add(ttl), LastTimestamp: l.LastTimestamp}
+		return t[id], nil
+	}
+	return Lease{}, fmt.Errorf("%w in [0, %d]", ErrNoFreeWorkerId, maxWorkerId)
+}
+
+func (t leaseTable) renew(workerId int64, owner string, ttl time.Duration, now time.Time) error {
+	l, ok := t[workerId]
+	if !ok || l.Owner == "" || l.Owner != owner || !l.Expires.After(now) {
+		return ErrLeaseLost
+	}
+	l.Expires = now.Add(ttl)
+	t[workerId] = l
+	return nil
+}
+
+// release keeps the entry without an owner, so the fence survives for the next owner.
+func (t leaseTable) release(workerId int64, owner string, lastTimestamp int64) error {
+	if l, ok := t[workerId]; ok && l.Owner == owner {
+		t[workerId] = Lease{WorkerId: workerId, LastTimestamp: max(l.LastTimestamp, lastTimestamp)}
+	}
+	return nil
+}
+
+// MemoryLeaseStore keeps leases in process memory, for tests and single-process setups.
+type MemoryLeaseStore struct {
+	Now    func() time.Time
+	mu     sync.Mutex
+	leases leaseTable
+}
+
+func NewMemoryLeaseStore() *MemoryLeaseStore {
+	return &MemoryLeaseStore{Now: time.Now, leases: leaseTable{}}
+}
+
+func (s *MemoryLeaseStore) Acquire(_ context.Context, owner string, maxWorkerId int64, ttl time.Duration) (Lease, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leases.acquire(owner, maxWorkerId, ttl, s.Now())
+}
+
+func (s *MemoryLeaseStore) Renew(_ context.Context, workerId int64, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leases.renew(workerId, owner, ttl, s.Now())
+}
+
+func (s *MemoryLeaseStore) Release(_ context.Context, workerId int64, owner string, lastTimestamp int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leases.release(workerId, owner, lastTimestamp)
+}
+
+// FileLeaseStore keeps leases in a JSON file guarded by an flock, so processes sharing a
+// filesystem (a host, or an NFS mount with lock support) never hold the same worker ID.
+type FileLeaseStore struct {
+	Path string
+	Now  func() time.Time
+}
+
+func NewFileLeaseStore(path string) *FileLeaseStore {
+	return &FileLeaseStore{Path: path, Now: time.Now}
+}
+
+func (s *FileLeaseStore) Acquire(_ context.Context, owner string, maxWorkerId int64, ttl time.Duration) (lease Lease, err error) {
+	err = s.update(func(t leaseTable) error {
+		lease, err = t.acquire(owner, maxWorkerId, ttl, s.Now())
+		return err
+	})
+	return lease, err
+}
+
+func (s *FileLeaseStore) Renew(_ context.Context, workerId int64, owner string, ttl time.Duration) error {
+	return s.update(func(t leaseTable) error {
+		return t.renew(workerId, owner, ttl, s.Now())
+	})
+}
+
+func (s *FileLeaseStore) Release(_ context.Context, workerId int64, owner string, lastTimestamp int64) error {
+	return s.update(func(t leaseTable) error {
+		return t.release(workerId, owner, lastTimestamp)
+	})
+}
+
+// update runs fn on the lease table under an exclusive lock and writes it back if fn succeeds.
+func (s *FileLeaseStore) update(fn func(leaseTable) error) error {
+	f, err := os.OpenFile(s.Path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("file lease store: %w", err)
+	}
+	defer f.Close()
+
+	if err = lockFile(f); err != nil {
+		return fmt.Errorf("file lease store: lock %s: %w", s.Path, err)
+	}
+	defer unlockFile(f)
+
+	var leases []Lease
+	if info, err := f.Stat(); err != nil {
+		return fmt.Errorf("file lease store: %w", err)
+	} else if info.Size() > 0 {
+		if err = json.NewDecoder(f).Decode(&leases); err != nil {
+			return fmt.Errorf("file lease store: decode %s: %w", s.Path, err)
+		}
+	}
+	table := leaseTable{}
+	for _, l := range leases {
+		table[l.WorkerId] = l
+	}
+
+	if err = fn(table); err != nil {
+		return err
+	}
+
+	leases = leases[:0]
+	for id := range table {
+		leases = append(leases, table[id])
+	}
+	sort.Slice(leases, func(i, j int) bool { return leases[i].WorkerId < leases[j].WorkerId })
+	data, err := json.Marshal(leases)
+	if err != nil {
+		return err
+	}
+	if err = f.Truncate(0); err != nil {
+		return fmt.Errorf("file lease store: %w", err)
+	}
+	if _, err = f.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("file lease store: %w", err)
+	}
+	return f.Sync()
+}
@@ -1,9 +1,218 @@
 package workers
 
-import "math/rand"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
 
-type LocalAssigner struct{}
+const (
+	DefaultLocalMaxWorkerId = 511
+)
 
-func (c *LocalAssigner) NextWorkerId() int64 {
-	return rand.Int63n(512)
+// liveSlots keeps claimed slot files reachable: callers usually drop the assigner once they have
+// the ID, and the *os.File finalizer would otherwise close the file and free the lock.
+var (
+	liveSlotsMu sync.Mutex
+	liveSlots   = map[*os.File]struct{}{}
+)
+
+// LocalAssigner claims a worker ID per process through flock'd slot files in a directory shared
+// by all processes on the host. The lock is held for the life of the process, so live processes
+// never share an ID and the slots of dead processes are freed by the kernel.
+type LocalAssigner struct {
+	Dir         string // defaults to $TMPDIR/atom-uid-workers
+	MaxWorkerId int64  // defaults to DefaultLocalMaxWorkerId
+
+	mu        sync.Mutex
+	slot      *os.File
+	workerId  int64
+	fence     int64
+	highWater int64
+}
+
+func NewLocalAssigner(dir string, maxWorkerId int64) *LocalAssigner {
+	return &LocalAssigner{Dir: dir, MaxWorkerId: maxWorkerId}
+}
+
+// SetMaxWorkerId lowers MaxWorkerId to the generator's budget.
+func (c *LocalAssigner) SetMaxWorkerId(maxWorkerId int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.MaxWorkerId <= 0 || c.MaxWorkerId > maxWorkerId {
+		c.MaxWorkerId = maxWorkerId
+	}
+}
+
+// NextWorkerIdContext locks the lowest free slot in [0, MaxWorkerId]. Calling it again while the
+// slot is held returns the same ID.
+func (c *LocalAssigner) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.slot != nil {
+		return c.workerId, nil
+	}
+
+	dir, maxWorkerId := c.Dir, c.MaxWorkerId
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "atom-uid-workers")
+	}
+	if maxWorkerId <= 0 {
+		maxWorkerId = DefaultLocalMaxWorkerId
+	}
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return 0, fmt.Errorf("local assigner: %w", err)
+	}
+
+	for id := int64(0); id <= maxWorkerId; id++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		// slot files are never removed: deleting a locked path lets two processes lock different inodes
+		f, err := os.OpenFile(filepath.Join(dir, "worker-"+strconv.FormatInt(id, 10)+".lock"), os.O_RDWR|os.O_CREATE, 0o666)
+		if err != nil {
+			return 0, fmt.Errorf("local assigner: %w", err)
+		}
+		locked, err := tryLockFile(f)
+		if err != nil || !locked {
+			_ = f.Close()
+			if err != nil {
+				return 0, fmt.Errorf("local assigner: lock slot %d: %w", id, err)
+			}
+			continue
+		}
+
+		// the slot file holds "<pid> <last timestamp> <high-water mark>", left by the previous owner
+		c.fence, c.highWater = readSlot(f)
+		if err = writeSlot(f, c.fence, c.highWater); err != nil {
+			_ = f.Close()
+			return 0, fmt.Errorf("local assigner: slot %d: %w", id, err)
+		}
+		c.slot, c.workerId = f, id
+		liveSlotsMu.Lock()
+		liveSlots[f] = struct{}{}
+		liveSlotsMu.Unlock()
+		return id, nil
+	}
+
+	return 0, fmt.Errorf("local assigner: %w in [0, %d] under %s", ErrNoFreeWorkerId, maxWorkerId, dir)
+}
+
+// FenceTimestamp returns the last timestamp the previous owner of the slot released it with.
+func (c *LocalAssigner) FenceTimestamp() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fence
+}
+
+// ReleaseWorkerId records lastTimestamp in the slot file and unlocks it.
+func (c *LocalAssigner) ReleaseWorkerId(_ context.Context, workerId, lastTimestamp int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.slot == nil || workerId != c.workerId {
+		return nil
+	}
+	err := writeSlot(c.slot, max(c.fence, lastTimestamp), c.highWater)
+	if err != nil {
+		err = fmt.Errorf("local assigner: %w", err)
+	}
+	return errors.Join(err, c.unlock())
+}
+
+// LoadHighWater returns the high-water mark kept in the slot file of the held ID.
+func (c *LocalAssigner) LoadHighWater(_ context.Context, workerId int64) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.slot == nil || workerId != c.workerId {
+		return 0, fmt.Errorf("local assigner: worker id %d is not held", workerId)
+	}
+	return c.highWater, nil
+}
+
+// SaveHighWater records mark in the slot file of the held ID and syncs it.
+func (c *LocalAssigner) SaveHighWater(_ context.Context, workerId, mark int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.slot == nil || workerId != c.workerId {
+		return fmt.Errorf("local assigner: worker id %d is not held", workerId)
+	}
+	err := writeSlot(c.slot, c.fence, mark)
+	if err == nil {
+		err = c.slot.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("local assigner: %w", err)
+	}
+	c.highWater = mark
+	return nil
+}
+
+// VerifyWorkerId checks that the slot is still locked and that its path still names the locked
+// file: once the slot file is removed or replaced, another process can lock the same ID.
+func (c *LocalAssigner) VerifyWorkerId(_ context.Context, workerId int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.slot == nil || workerId != c.workerId {
+		return fmt.Errorf("local assigner: worker id %d is not held", workerId)
+	}
+	locked, err := c.slot.Stat()
+	if err != nil {
+		return fmt.Errorf("local assigner: %w", err)
+	}
+	if onDisk, err := os.Stat(c.slot.Name()); err != nil || !os.SameFile(locked, onDisk) {
+		return fmt.Errorf("local assigner: slot file %s of worker id %d was removed or replaced", c.slot.Name(), workerId)
+	}
+	return nil
+}
+
+// Close unlocks the slot so another process can claim the ID.
+func (c *LocalAssigner) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unlock()
+}
+
+func (c *LocalAssigner) unlock() error {
+	if c.slot == nil {
+		return nil
+	}
+	liveSlotsMu.Lock()
+	delete(liveSlots, c.slot)
+	liveSlotsMu.Unlock()
+
+	err := errors.Join(unlockFile(c.slot), c.slot.Close())
+	c.slot = nil
+	return err
+}
+
+func readSlot(f *os.File) (fence, highWater int64) {
+	buf := make([]byte, 64)
+	n, _ := f.ReadAt(buf, 0)
+	fields := strings.Fields(string(buf[:n]))
+	if len(fields) > 1 {
+		fence, _ = strconv.ParseInt(fields[1], 10, 64)
+	}
+	if len(fields) > 2 {
+		highWater, _ = strconv.ParseInt(fields[2], 10, 64)
+	}
+	return fence, highWater
+}
+
+// writeSlot overwrites the slot in one write of a fixed-width record, wider than any record of the
+// former variable-width format, so no truncate is needed and a crash cannot leave it empty
+func writeSlot(f *os.File, fence, highWater int64) error {
+	_, err := f.WriteAt([]byte(fmt.Sprintf("%20d %20d %20d\n", os.Getpid(), fence, highWater)), 0)
+	return err
 }
@@ -0,0 +1,91 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalAssigner_NextWorkerIdContext(t *testing.T) {
+	ctx, dir := context.Background(), t.TempDir()
+
+	a, b := NewLocalAssigner(dir, 1), NewLocalAssigner(dir, 1)
+	idA, err := a.NextWorkerIdContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := b.NextWorkerIdContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idA == idB {
+		t.Fatalf("both assigners got worker id %d", idA)
+	}
+	if again, _ := a.NextWorkerIdContext(ctx); again != idA {
+		t.Fatalf("NextWorkerIdContext() = %d, want held id %d", again, idA)
+	}
+
+	c := NewLocalAssigner(dir, 1)
+	if _, err = c.NextWorkerIdContext(ctx); !errors.Is(err, ErrNoFreeWorkerId) {
+		t.Fatalf("NextWorkerIdContext() error = %v, want ErrNoFreeWorkerId", err)
+	}
+
+	if err = a.ReleaseWorkerId(ctx, idA, 1_700_000_000); err != nil {
+		t.Fatal(err)
+	}
+	if idC, err := c.NextWorkerIdContext(ctx); err != nil || idC != idA {
+		t.Fatalf("NextWorkerIdContext() = %d, %v, want freed id %d", idC, err, idA)
+	}
+	if fence := c.FenceTimestamp(); fence != 1_700_000_000 {
+		t.Fatalf("FenceTimestamp() = %d, want 1700000000", fence)
+	}
+	_ = b.Close()
+	_ = c.Close()
+}
+
+func TestLocalAssigner_VerifyWorkerId(t *testing.T) {
+	a := NewLocalAssigner(t.TempDir(), 3)
+	defer a.Close()
+	id, err := a.NextWorkerIdContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = a.VerifyWorkerId(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Remove(filepath.Join(a.Dir, "worker-0.lock")); err != nil {
+		t.Fatal(err)
+	}
+	if err = a.VerifyWorkerId(context.Background(), id); err == nil {
+		t.Fatal("expected error after the slot file was removed")
+	}
+}
+
+func TestLocalAssigner_SlotRecord(t *testing.T) {
+	ctx, dir := context.Background(), t.TempDir()
+	// a slot left in the former variable-width format
+	if err := os.WriteFile(filepath.Join(dir, "worker-0.lock"), []byte("123 42 1700000005000"), 0o666); err != nil {
+		t.Fatal(err)
+	}
+	assigner := NewLocalAssigner(dir, 3)
+	if _, err := assigner.NextWorkerIdContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if fence, mark := assigner.FenceTimestamp(), assigner.highWater; fence != 42 || mark != 1_700_000_005_000 {
+		t.Fatalf("read fence %d, mark %d, want 42 and 1700000005000", fence, mark)
+	}
+
+	// a lower mark replaces a longer one without leaving digits of it behind
+	if err := assigner.SaveHighWater(ctx, 0, 7); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "worker-0.lock"))
+	if fields := strings.Fields(string(data)); len(data) != 63 || len(fields) != 3 || fields[1] != "42" || fields[2] != "7" {
+		t.Errorf("slot = %q, want one fixed-width record of fence 42 and mark 7", data)
+	}
+	_ = assigner.ReleaseWorkerId(ctx, 0, 0)
+}
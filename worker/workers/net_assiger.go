@@ -0,0 +1,166 @@
+package workers
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NetSource selects which address the worker ID is derived from.
+type NetSource uint
+
+const (
+	IPv4Source NetSource = iota // private IPv4 address
+	MACSource                   // hardware address
+)
+
+const (
+	DefaultNetBits = 16
+)
+
+// NetInterface is the subset of net.Interface the NetAssigner looks at.
+type NetInterface struct {
+	Name         string
+	Flags        net.Flags
+	HardwareAddr net.HardwareAddr
+	Addrs        []net.Addr
+}
+
+// NetAssigner derives the worker ID from the low bits of the host's private IPv4 address or MAC,
+// like Sonyflake's default machine ID. It needs no coordination service, but hosts whose low
+// address bits collide get the same ID, so the address plan must keep them unique.
+type NetAssigner struct {
+	Source    NetSource
+	Interface string // only look at this interface, required on multi-homed hosts
+	Bits      int    // low address bits used, defaults to the worker budget or DefaultNetBits
+
+	// Interfaces lists the host's interfaces, defaults to net.Interfaces.
+	Interfaces func() ([]NetInterface, error)
+
+	mu          sync.Mutex
+	maxWorkerId int64
+}
+
+func (c *NetAssigner) SetMaxWorkerId(maxWorkerId int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxWorkerId = maxWorkerId
+}
+
+func (c *NetAssigner) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	maxWorkerId := c.maxWorkerId
+	c.mu.Unlock()
+
+	n := c.Bits
+	budget := bits.Len64(uint64(maxWorkerId))
+	if n <= 0 {
+		n = DefaultNetBits
+		if maxWorkerId > 0 {
+			n = budget
+		}
+	}
+	if maxWorkerId > 0 && n > budget {
+		return 0, fmt.Errorf("net assigner: %d address bits exceed max worker id %d (%d bits)", n, maxWorkerId, budget)
+	}
+	if n > 48 || (c.Source == IPv4Source && n > 32) {
+		return 0, fmt.Errorf("net assigner: %d bits is more than the address holds", n)
+	}
+
+	candidates, err := c.candidates()
+	if err != nil {
+		return 0, err
+	}
+	switch len(candidates) {
+	case 0:
+		return 0, errors.New("net assigner: no usable address found" + c.scope())
+	case 1:
+		for _, v := range candidates {
+			return int64(v & (1<<uint(n) - 1)), nil
+		}
+	}
+
+	names := make([]string, 0, len(candidates))
+	for name := range candidates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return 0, fmt.Errorf("net assigner: ambiguous multi-homed host, candidates %s; set Interface", strings.Join(names, ", "))
+}
+
+// candidates maps "iface/address" to the address as an integer.
+func (c *NetAssigner) candidates() (map[string]uint64, error) {
+	list := c.Interfaces
+	if list == nil {
+		list = systemInterfaces
+	}
+	ifaces, err := list()
+	if err != nil {
+		return nil, fmt.Errorf("net assigner: %w", err)
+	}
+
+	found := map[string]uint64{}
+	for _, iface := range ifaces {
+		if c.Interface != "" && iface.Name != c.Interface {
+			continue
+		}
+		if c.Interface == "" && (iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0) {
+			continue
+		}
+
+		if c.Source == MACSource {
+			if len(iface.HardwareAddr) == 6 {
+				mac := append([]byte{0, 0}, iface.HardwareAddr...)
+				found[iface.Name+"/"+iface.HardwareAddr.String()] = binary.BigEndian.Uint64(mac)
+			}
+			continue
+		}
+		for _, addr := range iface.Addrs {
+			var ip net.IP
+			switch v := addr.(type) {
+			case *net.IPNet:
+				ip = v.IP
+			case *net.IPAddr:
+				ip = v.IP
+			}
+			if ip4 := ip.To4(); ip4 != nil && ip4.IsPrivate() {
+				found[iface.Name+"/"+ip4.String()] = uint64(binary.BigEndian.Uint32(ip4))
+			}
+		}
+	}
+	return found, nil
+}
+
+func (c *NetAssigner) scope() string {
+	if c.Interface != "" {
+		return " on interface " + c.Interface
+	}
+	return ""
+}
+
+func systemInterfaces() ([]NetInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]NetInterface, 0, len(ifaces))
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, NetInterface{Name: iface.Name, Flags: iface.Flags, HardwareAddr: iface.HardwareAddr, Addrs: addrs})
+	}
+	return list, nil
+}
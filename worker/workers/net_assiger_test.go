@@ -0,0 +1,52 @@
+package workers
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func ifaces(list ...NetInterface) func() ([]NetInterface, error) {
+	return func() ([]NetInterface, error) { return list, nil }
+}
+
+func ipNet(s string) net.Addr {
+	ip, n, _ := net.ParseCIDR(s)
+	n.IP = ip
+	return n
+}
+
+func TestNetAssigner_NextWorkerIdContext(t *testing.T) {
+	up := net.FlagUp
+	mac, _ := net.ParseMAC("02:42:ac:11:01:02")
+	lo := NetInterface{Name: "lo", Flags: up | net.FlagLoopback, Addrs: []net.Addr{ipNet("127.0.0.1/8")}}
+	eth0 := NetInterface{Name: "eth0", Flags: up, HardwareAddr: mac, Addrs: []net.Addr{ipNet("10.0.3.7/16"), ipNet("8.8.8.8/32")}}
+	eth1 := NetInterface{Name: "eth1", Flags: up, Addrs: []net.Addr{ipNet("192.168.1.9/24")}}
+
+	tests := []struct {
+		name     string
+		assigner *NetAssigner
+		max      int64
+		want     int64
+		wantErr  bool
+	}{
+		{name: "ipv4 default bits", assigner: &NetAssigner{Interfaces: ifaces(lo, eth0)}, want: 3<<8 | 7},
+		{name: "ipv4 budget bits", assigner: &NetAssigner{Interfaces: ifaces(lo, eth0)}, max: 255, want: 7},
+		{name: "mac", assigner: &NetAssigner{Source: MACSource, Interfaces: ifaces(lo, eth0)}, max: 1023, want: 0x102},
+		{name: "bits exceed budget", assigner: &NetAssigner{Bits: 16, Interfaces: ifaces(eth0)}, max: 255, wantErr: true},
+		{name: "multi-homed", assigner: &NetAssigner{Interfaces: ifaces(eth0, eth1)}, wantErr: true},
+		{name: "pinned interface", assigner: &NetAssigner{Interface: "eth1", Interfaces: ifaces(eth0, eth1)}, max: 255, want: 9},
+		{name: "no address", assigner: &NetAssigner{Interfaces: ifaces(lo)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.max > 0 {
+				tt.assigner.SetMaxWorkerId(tt.max)
+			}
+			got, err := tt.assigner.NextWorkerIdContext(context.Background())
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("NextWorkerIdContext() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
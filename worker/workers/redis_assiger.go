@@ -0,0 +1,120 @@
+package workers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultRedisKeyPrefix = "uid:worker"
+)
+
+// renewScript extends KEYS[1] by ARGV[2] ms only while ARGV[1] still owns it.
+const renewScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then return redis.call('PEXPIRE', KEYS[1], ARGV[2]) end return 0`
+
+// releaseScript deletes KEYS[1] only while ARGV[1] owns it, first raising the mark in KEYS[2] to ARGV[2].
+const releaseScript = `if redis.call('GET', KEYS[1]) ~= ARGV[1] then return 0 end
+local last = tonumber(ARGV[2])
+if last > 0 and last > (tonumber(redis.call('GET', KEYS[2]) or '0') or 0) then redis.call('SET', KEYS[2], ARGV[2]) end
+return redis.call('DEL', KEYS[1])`
+
+// RedisLeaseStore keeps leases as "<prefix>:<id>" keys claimed with SET NX PX, so Redis expires
+// the lease when its owner stops renewing. The last timestamp released with an ID is kept in
+// "<prefix>:<id>:last" without expiry.
+type RedisLeaseStore struct {
+	KeyPrefix string // defaults to DefaultRedisKeyPrefix
+	client    *respClient
+}
+
+// NewRedisLeaseStore talks RESP to addr, authenticating with password and selecting db if set.
+func NewRedisLeaseStore(addr, password string, db int) *RedisLeaseStore {
+	return &RedisLeaseStore{client: &respClient{addr: addr, password: password, db: db, timeout: 5 * time.Second}}
+}
+
+// NewRedisAssigner is a LeaseAssigner over a RedisLeaseStore.
+func NewRedisAssigner(addr, password string, db int, maxWorkerId int64, ttl time.Duration) *LeaseAssigner {
+	return NewLeaseAssigner(NewRedisLeaseStore(addr, password, db), maxWorkerId, ttl)
+}
+
+// NewRedisAssignerFromEnv reads $REDIS_ADDR (default 127.0.0.1:6379), $REDIS_PASSWORD and $REDIS_DB.
+func NewRedisAssignerFromEnv() *LeaseAssigner {
+	addr := os.Getenv("REDIS_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:6379"
+	}
+	db, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+	return NewRedisAssigner(addr, os.Getenv("REDIS_PASSWORD"), db, 0, DefaultLeaseTTL)
+}
+
+func (s *RedisLeaseStore) key(workerId int64) string {
+	prefix := s.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultRedisKeyPrefix
+	}
+	return prefix + ":" + strconv.FormatInt(workerId, 10)
+}
+
+func (s *RedisLeaseStore) Acquire(ctx context.Context, owner string, maxWorkerId int64, ttl time.Duration) (Lease, error) {
+	if ttl <= 0 {
+		return Lease{}, errInvalidLeaseTTL
+	}
+	px := strconv.FormatInt(ttl.Milliseconds(), 10)
+
+	for id := int64(0); id <= maxWorkerId; id++ {
+		key := s.key(id)
+		reply, err := s.client.do(ctx, "SET", key, owner, "NX", "PX", px)
+		if err != nil {
+			return Lease{}, fmt.Errorf("redis lease store: %w", err)
+		}
+		if reply == nil {
+			// held by a live node, unless it is our own lease from before a restart of the assigner
+			if reply, err = s.client.do(ctx, "EVAL", renewScript, "1", key, owner, px); err != nil {
+				return Lease{}, fmt.Errorf("redis lease store: %w", err)
+			}
+			if reply != int64(1) {
+				continue
+			}
+		}
+
+		// a retry by the same owner reclaims the lease if reading the mark fails
+		last, err := s.client.do(ctx, "GET", key+":last")
+		if err != nil {
+			return Lease{}, fmt.Errorf("redis lease store: %w", err)
+		}
+		lease := Lease{WorkerId: id, Owner: owner, Expires: time.Now().Add(ttl)}
+		if last != nil {
+			lease.LastTimestamp, _ = strconv.ParseInt(fmt.Sprint(last), 10, 64)
+		}
+		return lease, nil
+	}
+	return Lease{}, fmt.Errorf("%w in [0, %d]", ErrNoFreeWorkerId, maxWorkerId)
+}
+
+// Renew extends the lease with a compare-and-pexpire script, so it never extends another owner's lease.
+func (s *RedisLeaseStore) Renew(ctx context.Context, workerId int64, owner string, ttl time.Duration) error {
+	reply, err := s.client.do(ctx, "EVAL", renewScript, "1", s.key(workerId), owner, strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return fmt.Errorf("redis lease store: %w", err)
+	}
+	if reply != int64(1) {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Release raises the ":last" mark and deletes the lease in one compare-and-delete script.
+func (s *RedisLeaseStore) Release(ctx context.Context, workerId int64, owner string, lastTimestamp int64) error {
+	key := s.key(workerId)
+	if _, err := s.client.do(ctx, "EVAL", releaseScript, "2", key, key+":last", owner, strconv.FormatInt(lastTimestamp, 10)); err != nil {
+		return fmt.Errorf("redis lease store: %w", err)
+	}
+	return nil
+}
+
+// Close closes the connection to Redis.
+func (s *RedisLeaseStore) Close() error {
+	return s.client.close()
+}
@@ -0,0 +1,235 @@
+package workers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// respServer is an in-process stand-in for Redis that understands the commands RedisLeaseStore sends.
+type respServer struct {
+	ln       net.Listener
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func startRespServer(t *testing.T, password string) *respServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &respServer{ln: ln, password: password, values: map[string]string{}, expires: map[string]time.Time{}}
+	t.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *respServer) addr() string { return s.ln.Addr().String() }
+
+func (s *respServer) serve(conn net.Conn) {
+	defer conn.Close()
+	rd, authed := bufio.NewReader(conn), s.password == ""
+	for {
+		reply, err := readReply(rd)
+		if err != nil {
+			return
+		}
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		if len(args) == 0 {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			_, _ = conn.Write([]byte("-NOAUTH Authentication required.\r\n"))
+			continue
+		}
+		if cmd == "AUTH" {
+			authed = len(args) == 2 && args[1] == s.password
+		}
+		_, _ = conn.Write([]byte(s.exec(cmd, args[1:], authed)))
+	}
+}
+
+func (s *respServer) exec(cmd string, args []string, authed bool) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, at := range s.expires {
+		if !time.Now().Before(at) {
+			delete(s.values, key)
+			delete(s.expires, key)
+		}
+	}
+	switch cmd {
+	case "AUTH":
+		if !authed {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "PING", "SELECT":
+		return "+OK\r\n"
+	case "GET":
+		if v, ok := s.values[args[0]]; ok {
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+		}
+		return "$-1\r\n"
+	case "SET":
+		key, nx, ttl := args[0], false, time.Duration(0)
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				ms, _ := strconv.Atoi(args[i+1])
+				ttl, i = time.Duration(ms)*time.Millisecond, i+1
+			}
+		}
+		if _, ok := s.values[key]; ok && nx {
+			return "$-1\r\n"
+		}
+		s.values[key] = args[1]
+		delete(s.expires, key)
+		if ttl > 0 {
+			s.expires[key] = time.Now().Add(ttl)
+		}
+		return "+OK\r\n"
+	case "PEXPIRE":
+		if _, ok := s.values[args[0]]; !ok {
+			return ":0\r\n"
+		}
+		ms, _ := strconv.Atoi(args[1])
+		s.expires[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return ":1\r\n"
+	case "EVAL":
+		// only the store's own scripts, run atomically under s.mu like Redis runs Lua
+		keys, argv := args[2:3], args[3:]
+		if args[1] == "2" {
+			keys, argv = args[2:4], args[4:]
+		}
+		if s.values[keys[0]] != argv[0] {
+			return ":0\r\n"
+		}
+		switch args[0] {
+		case renewScript:
+			ms, _ := strconv.Atoi(argv[1])
+			s.expires[keys[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+			return ":1\r\n"
+		case releaseScript:
+			last, _ := strconv.ParseInt(argv[1], 10, 64)
+			if prev, _ := strconv.ParseInt(s.values[keys[1]], 10, 64); last > 0 && last > prev {
+				s.values[keys[1]] = argv[1]
+			}
+			delete(s.values, keys[0])
+			delete(s.expires, keys[0])
+			return ":1\r\n"
+		}
+		return "-NOSCRIPT unknown script\r\n"
+	case "DEL":
+		_, ok := s.values[args[0]]
+		delete(s.values, args[0])
+		delete(s.expires, args[0])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	}
+	return "-ERR unknown command '" + cmd + "'\r\n"
+}
+
+func (s *respServer) del(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	delete(s.expires, key)
+}
+
+func TestRedisLeaseStore(t *testing.T) {
+	server := startRespServer(t, "secret")
+	store := NewRedisLeaseStore(server.addr(), "secret", 1)
+	defer store.Close()
+	ctx := context.Background()
+
+	a, err := store.Acquire(ctx, "a", 1, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := store.Acquire(ctx, "b", 1, time.Minute)
+	if a.WorkerId != 0 || b.WorkerId != 1 {
+		t.Fatalf("Acquire() = %d, %d, want 0, 1", a.WorkerId, b.WorkerId)
+	}
+	if _, err = store.Acquire(ctx, "c", 1, time.Minute); !errors.Is(err, ErrNoFreeWorkerId) {
+		t.Fatalf("Acquire() error = %v, want ErrNoFreeWorkerId", err)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if c, err := store.Acquire(ctx, "c", 1, time.Minute); err != nil || c.WorkerId != a.WorkerId {
+		t.Fatalf("Acquire() = %d, %v, want expired id %d", c.WorkerId, err, a.WorkerId)
+	}
+	if err = store.Renew(ctx, a.WorkerId, "a", time.Minute); !errors.Is(err, ErrLeaseLost) {
+		t.Fatalf("Renew() error = %v, want ErrLeaseLost", err)
+	}
+
+	if err = store.Release(ctx, b.WorkerId, "b", 1_700_000_123); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Release(ctx, a.WorkerId, "a", 1_800_000_000); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Renew(ctx, a.WorkerId, "c", time.Minute); err != nil {
+		t.Fatalf("Renew() error = %v, a stale owner's release must leave the lease alone", err)
+	}
+	d, _ := store.Acquire(ctx, "d", 1, time.Minute)
+	if d.WorkerId != b.WorkerId || d.LastTimestamp != 1_700_000_123 {
+		t.Fatalf("Acquire() = %+v, want released id %d fenced at 1700000123", d, b.WorkerId)
+	}
+
+	if _, err = NewRedisLeaseStore(server.addr(), "wrong", 0).Acquire(ctx, "e", 1, time.Minute); err == nil {
+		t.Fatal("expected error for wrong password")
+	}
+}
+
+func TestRedisAssigner_Lost(t *testing.T) {
+	server := startRespServer(t, "")
+	lost := make(chan error, 1)
+	assigner := NewRedisAssigner(server.addr(), "", 0, 3, time.Minute)
+	assigner.Heartbeat = 10 * time.Millisecond
+	assigner.OnLost = func(_ int64, err error) { lost <- err }
+	defer assigner.Close()
+
+	id, err := assigner.NextWorkerIdContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.del(DefaultRedisKeyPrefix + ":" + strconv.FormatInt(id, 10))
+
+	select {
+	case err = <-lost:
+		if !errors.Is(err, ErrLeaseLost) {
+			t.Fatalf("lost error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("lease loss was not detected")
+	}
+}
@@ -0,0 +1,174 @@
+package workers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultStaticFileEnv = "WORKER_ID_FILE"
+	DefaultStaticFile    = "worker_ids.json"
+)
+
+// StaticAssigner looks the host up in a hand-maintained JSON file mapping hostname, IP or
+// instance name to worker ID, e.g. {"web-1": 1, "10.0.0.12": 2}. The whole file is checked on
+// load: a duplicate name, two names sharing an ID or an ID outside the layout's range fail
+// startup, as does a host that is not listed.
+type StaticAssigner struct {
+	Path  string   // defaults to $WORKER_ID_FILE, then DefaultStaticFile
+	Names []string // names this host is listed under, defaults to its hostname and private IPv4 addresses
+
+	// Interfaces lists the host's interfaces for the default Names, defaults to net.Interfaces.
+	Interfaces func() ([]NetInterface, error)
+
+	mu          sync.Mutex
+	maxWorkerId int64
+}
+
+func NewStaticAssigner(path string, names ...string) *StaticAssigner {
+	return &StaticAssigner{Path: path, Names: names}
+}
+
+func (c *StaticAssigner) SetMaxWorkerId(maxWorkerId int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxWorkerId = maxWorkerId
+}
+
+func (c *StaticAssigner) NextWorkerIdContext(ctx context.Context) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	maxWorkerId := c.maxWorkerId
+	c.mu.Unlock()
+
+	path := c.Path
+	if path == "" {
+		path = os.Getenv(DefaultStaticFileEnv)
+	}
+	if path == "" {
+		path = DefaultStaticFile
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("static assigner: %w", err)
+	}
+	defer f.Close()
+
+	ids, err := LoadStaticWorkerIds(f, maxWorkerId)
+	if err != nil {
+		return 0, fmt.Errorf("static assigner: %s: %w", path, err)
+	}
+
+	names, err := c.names()
+	if err != nil {
+		return 0, fmt.Errorf("static assigner: %w", err)
+	}
+	var matched []string
+	for _, name := range names {
+		if _, ok := ids[name]; ok {
+			matched = append(matched, name)
+		}
+	}
+	if len(matched) == 0 {
+		return 0, fmt.Errorf("static assigner: host is not listed in %s as any of %s", path, strings.Join(names, ", "))
+	}
+	// the same host under two names is fine, but only if both carry the same ID
+	for _, name := range matched[1:] {
+		if ids[name] != ids[matched[0]] {
+			return 0, fmt.Errorf("static assigner: host is listed as %s=%d and %s=%d in %s",
+				matched[0], ids[matched[0]], name, ids[name], path)
+		}
+	}
+	return ids[matched[0]], nil
+}
+
+// LoadStaticWorkerIds reads a name to worker ID JSON object, rejecting duplicate names, shared IDs
+// and, when maxWorkerId is positive, IDs outside [0, maxWorkerId].
+func LoadStaticWorkerIds(r io.Reader, maxWorkerId int64) (map[string]int64, error) {
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, errors.New("expected a JSON object of name to worker id")
+	}
+
+	ids, owners := map[string]int64{}, map[int64]string{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSpace(tok.(string))
+		var id int64
+		if err = dec.Decode(&id); err != nil {
+			return nil, fmt.Errorf("worker id of %q: %w", name, err)
+		}
+
+		if name == "" {
+			return nil, errors.New("empty name")
+		}
+		if _, ok := ids[name]; ok {
+			return nil, fmt.Errorf("%q is listed twice", name)
+		}
+		if owner, ok := owners[id]; ok {
+			return nil, fmt.Errorf("worker id %d is assigned to both %q and %q", id, owner, name)
+		}
+		if id < 0 || (maxWorkerId > 0 && id > maxWorkerId) {
+			return nil, fmt.Errorf("worker id %d of %q is outside [0, %d]", id, name, maxWorkerId)
+		}
+		ids[name], owners[id] = id, name
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (c *StaticAssigner) names() ([]string, error) {
+	if len(c.Names) > 0 {
+		return c.Names, nil
+	}
+
+	seen := map[string]bool{}
+	if host := os.Getenv("HOSTNAME"); host != "" {
+		seen[host] = true
+	}
+	if host, err := os.Hostname(); err == nil {
+		seen[host] = true
+	}
+
+	list := c.Interfaces
+	if list == nil {
+		list = systemInterfaces
+	}
+	ifaces, err := list()
+	if err != nil {
+		return nil, err
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		for _, addr := range iface.Addrs {
+			if v, ok := addr.(*net.IPNet); ok && v.IP.To4() != nil && v.IP.IsPrivate() {
+				seen[v.IP.String()] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,74 @@
+package workers
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadStaticWorkerIds(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+	}{
+		{name: "ok", json: `{"web-1": 1, "10.0.0.12": 2, "db": 0}`},
+		{name: "duplicate name", json: `{"web-1": 1, "web-1": 2}`, wantErr: "listed twice"},
+		{name: "shared id", json: `{"web-1": 1, "web-2": 1}`, wantErr: "assigned to both"},
+		{name: "out of range", json: `{"web-1": 8}`, wantErr: "outside [0, 7]"},
+		{name: "negative", json: `{"web-1": -1}`, wantErr: "outside"},
+		{name: "not an object", json: `[1, 2]`, wantErr: "JSON object"},
+		{name: "not an integer", json: `{"web-1": "1"}`, wantErr: "worker id of"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadStaticWorkerIds(strings.NewReader(tt.json), 7)
+			if (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("LoadStaticWorkerIds() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStaticAssigner_NextWorkerIdContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "worker_ids.json")
+	if err := os.WriteFile(path, []byte(`{"web-1": 1, "10.0.0.12": 2, "web-2": 3}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ifaces := func() ([]NetInterface, error) {
+		return []NetInterface{{Name: "eth0", Flags: net.FlagUp, Addrs: []net.Addr{
+			&net.IPNet{IP: net.IPv4(10, 0, 0, 12), Mask: net.CIDRMask(24, 32)},
+		}}}, nil
+	}
+
+	tests := []struct {
+		name     string
+		assigner *StaticAssigner
+		want     int64
+		wantErr  bool
+	}{
+		{name: "hostname", assigner: NewStaticAssigner(path, "web-1"), want: 1},
+		{name: "ip", assigner: &StaticAssigner{Path: path, Interfaces: ifaces}, want: 2},
+		{name: "not listed", assigner: NewStaticAssigner(path, "web-9"), wantErr: true},
+		{name: "conflicting names", assigner: NewStaticAssigner(path, "web-1", "web-2"), wantErr: true},
+		{name: "missing file", assigner: NewStaticAssigner(path+".missing", "web-1"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.assigner.SetMaxWorkerId(7)
+			got, err := tt.assigner.NextWorkerIdContext(context.Background())
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("NextWorkerIdContext() = %d, %v, want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	narrow := NewStaticAssigner(path, "web-1")
+	narrow.SetMaxWorkerId(1)
+	if _, err := narrow.NextWorkerIdContext(context.Background()); err == nil {
+		t.Error("expected error for ids outside the layout's range")
+	}
+}